@@ -0,0 +1,197 @@
+package dbtools
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ArrayCol maps a Postgres array column (int[], text[], ...), taking the
+// array literal from FromIntSlice or FromStringSlice, like `{1,2,3}`. FindInto
+// parses it back into an []int, []int64 or []string field. Only Postgres has
+// arrays; with any other dialect, queries on the TableMap fail. ArrayCol
+// doesn't know the element type, so CreateTableSql can't declare the column;
+// use IntArrayCol or StringArrayCol for that.
+func (f *TableMap) ArrayCol(name string, input TableMapInput) {
+	f.arrayCol(name, kindUnknown, input)
+}
+
+// IntArrayCol is an ArrayCol of INTEGER[], for FromIntSlice.
+func (f *TableMap) IntArrayCol(name string, input TableMapInput) {
+	f.arrayCol(name, kindIntArray, input)
+}
+
+// StringArrayCol is an ArrayCol of TEXT[], for FromStringSlice.
+func (f *TableMap) StringArrayCol(name string, input TableMapInput) {
+	f.arrayCol(name, kindStringArray, input)
+}
+
+func (f *TableMap) arrayCol(name string, kind colKind, input TableMapInput) {
+	check := func(d Dialect) error {
+		if d != DialectPostgres {
+			return fmt.Errorf("dbtools: array column %s is only supported on Postgres", name)
+		}
+		return nil
+	}
+
+	read := func(src sql.NullString, dest interface{}) error {
+		if !src.Valid {
+			return nil
+		}
+
+		elems, err := parseArray(src.String)
+		if err != nil {
+			return fmt.Errorf("dbtools: reading array column %s: %w", name, err)
+		}
+
+		err = setArray(dest, elems)
+		if err != nil {
+			return fmt.Errorf("dbtools: reading array column %s: %w", name, err)
+		}
+		return nil
+	}
+
+	f.addField(name, TableMapField{Val: input, read: read, check: check, kind: kind})
+}
+
+// FromIntSlice treats a nil pointer or slice as null; an empty slice is `{}`.
+func FromIntSlice(v *[]int) TableMapInput {
+	return func() sql.NullString {
+		if v == nil || *v == nil {
+			return sql.NullString{String: "", Valid: false}
+		}
+
+		elems := make([]string, len(*v))
+		for i, n := range *v {
+			elems[i] = strconv.Itoa(n)
+		}
+		return sql.NullString{String: "{" + strings.Join(elems, ",") + "}", Valid: true}
+	}
+}
+
+// FromStringSlice quotes every element, so commas, braces and the word NULL
+// come through as plain text.
+func FromStringSlice(v *[]string) TableMapInput {
+	return func() sql.NullString {
+		if v == nil || *v == nil {
+			return sql.NullString{String: "", Valid: false}
+		}
+
+		elems := make([]string, len(*v))
+		for i, s := range *v {
+			s = strings.Replace(s, `\`, `\\`, -1)
+			s = strings.Replace(s, `"`, `\"`, -1)
+			elems[i] = `"` + s + `"`
+		}
+		return sql.NullString{String: "{" + strings.Join(elems, ",") + "}", Valid: true}
+	}
+}
+
+// arrayElem is one element of a parsed array literal.
+type arrayElem struct {
+	s    string
+	null bool
+}
+
+// parseArray splits a one-dimensional Postgres array literal into its
+// elements, unquoting them. Nested arrays aren't supported.
+func parseArray(lit string) ([]arrayElem, error) {
+	if len(lit) < 2 || lit[0] != '{' || lit[len(lit)-1] != '}' {
+		return nil, fmt.Errorf("malformed array literal %q", lit)
+	}
+	body := lit[1 : len(lit)-1]
+	if strings.TrimSpace(body) == "" {
+		return []arrayElem{}, nil
+	}
+
+	var elems []arrayElem
+	for i := 0; i <= len(body); {
+		// skip leading whitespace
+		for i < len(body) && body[i] == ' ' {
+			i++
+		}
+
+		var elem arrayElem
+		if i < len(body) && body[i] == '"' {
+			var b strings.Builder
+			i++
+			for ; i < len(body) && body[i] != '"'; i++ {
+				if body[i] == '\\' && i+1 < len(body) {
+					i++
+				}
+				b.WriteByte(body[i])
+			}
+			if i == len(body) {
+				return nil, fmt.Errorf("unterminated quote in array literal %q", lit)
+			}
+			i++
+			elem.s = b.String()
+		} else {
+			j := strings.IndexByte(body[i:], ',')
+			if j < 0 {
+				j = len(body) - i
+			}
+			raw := strings.TrimSpace(body[i : i+j])
+			if strings.ContainsAny(raw, "{}") {
+				return nil, errors.New("nested arrays aren't supported")
+			}
+			if raw == "" {
+				return nil, fmt.Errorf("empty element in array literal %q", lit)
+			}
+			elem.s = raw
+			elem.null = strings.EqualFold(raw, "NULL")
+			i += j
+		}
+		elems = append(elems, elem)
+
+		for i < len(body) && body[i] == ' ' {
+			i++
+		}
+		if i < len(body) && body[i] != ',' {
+			return nil, fmt.Errorf("malformed array literal %q", lit)
+		}
+		i++
+	}
+	return elems, nil
+}
+
+// setArray fills the slice field dest points to (or points to a pointer to)
+// from elems.
+func setArray(dest interface{}, elems []arrayElem) error {
+	field := reflect.ValueOf(dest).Elem()
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+	if field.Kind() != reflect.Slice {
+		return fmt.Errorf("can't read an array into a field of type %s", field.Type())
+	}
+
+	out := reflect.MakeSlice(field.Type(), len(elems), len(elems))
+	for i, elem := range elems {
+		if elem.null {
+			return errors.New("NULL elements aren't supported")
+		}
+
+		v := out.Index(i)
+		switch v.Kind() {
+		case reflect.String:
+			v.SetString(elem.s)
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.ParseInt(elem.s, 10, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetInt(n)
+		default:
+			return fmt.Errorf("can't read an array into a field of type %s", field.Type())
+		}
+	}
+	field.Set(out)
+	return nil
+}
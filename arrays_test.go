@@ -0,0 +1,84 @@
+package dbtools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseArray(t *testing.T) {
+	tests := []struct {
+		lit  string
+		want []arrayElem
+		err  bool
+	}{
+		{lit: `{}`, want: []arrayElem{}},
+		{lit: `{ }`, want: []arrayElem{}},
+		{lit: `{1,2,3}`, want: []arrayElem{{s: "1"}, {s: "2"}, {s: "3"}}},
+		{lit: `{1, 2}`, want: []arrayElem{{s: "1"}, {s: "2"}}},
+		{lit: `{a,NULL}`, want: []arrayElem{{s: "a"}, {s: "NULL", null: true}}},
+		{lit: `{"a,b","{c}"}`, want: []arrayElem{{s: "a,b"}, {s: "{c}"}}},
+		{lit: `{"NULL"}`, want: []arrayElem{{s: "NULL"}}},
+		{lit: `{""}`, want: []arrayElem{{s: ""}}},
+		{lit: `{"say \"hi\"","back\\slash"}`, want: []arrayElem{{s: `say "hi"`}, {s: `back\slash`}}},
+		{lit: `{1,}`, err: true},
+		{lit: `{,1}`, err: true},
+		{lit: `{"a}`, err: true},
+		{lit: `{"a"b}`, err: true},
+		{lit: `{{1},{2}}`, err: true},
+		{lit: `1,2`, err: true},
+		{lit: ``, err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseArray(tt.lit)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseArray(%q) = %v, want an error", tt.lit, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseArray(%q): %v", tt.lit, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseArray(%q) = %v, want %v", tt.lit, got, tt.want)
+		}
+	}
+}
+
+func TestStringSliceRoundTrip(t *testing.T) {
+	in := []string{`a,b`, `say "hi"`, `back\slash`, "NULL", ""}
+	lit := FromStringSlice(&in)()
+	elems, err := parseArray(lit.String)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	if err := setArray(&out, elems); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip through %q = %q, want %q", lit.String, out, in)
+	}
+}
+
+func TestCreateTableWithArrays(t *testing.T) {
+	type tagged struct {
+		ID   int `db:"id,pk"`
+		Tags []string
+		Nums []int
+	}
+	tm, err := NewTableMapFromStruct(nil, "tagged", &tagged{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.SetDialect(DialectPostgres)
+
+	query, err := tm.CreateTableSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `CREATE TABLE "tagged" ("id" INTEGER, "tags" TEXT[], "nums" INTEGER[], PRIMARY KEY ("id"))`; query != want {
+		t.Errorf("CreateTableSql = %q, want %q", query, want)
+	}
+}
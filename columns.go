@@ -0,0 +1,865 @@
+package dbtools
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type TableMapField struct {
+	Val TableMapInput
+	// Value, when set, is bound to the statement instead of Val's string. Val
+	// is still used for the null check and for display.
+	Value TableMapValue
+	// read, when set, decodes the column's value into a struct field on
+	// FindInto.
+	read TableMapOutput
+	// check, when set, reports why the current value can't be stored in the
+	// dialect; Val yields null in that case. Errors go through check rather
+	// than setErr so they follow the TableMap through Clone and WithTx.
+	check func(d Dialect) error
+	// kind is what the column was mapped as, and scale its decimal places if
+	// it's a DecimalCol. CreateTableSql picks the SQL type from them.
+	kind  colKind
+	scale int
+	// def and defSql are what Create inserts instead when the value is null;
+	// see Default and DefaultSql.
+	def    TableMapValue
+	defSql string
+	// validators run on the value before it's written; see Validate.
+	validators []func(v sql.NullString) error
+}
+
+type TableMapInput func() sql.NullString
+
+// TableMapOutput is the read side of a TableMapInput: it decodes a column's
+// value, as text, into dest, the address of the struct field FindInto is
+// filling. See ReadTransform.
+type TableMapOutput func(src sql.NullString, dest interface{}) error
+
+// TableMapValue is a TableMapInput that yields the value itself rather than a
+// string, so it reaches the driver as its native type. That matters for data
+// like []byte that a string would corrupt. ok is false for null.
+type TableMapValue func() (v interface{}, ok bool)
+
+// arg is the value to bind for the field; a nil arg is bound as a real NULL.
+func (t TableMapField) arg() (interface{}, bool) {
+	if t.Value != nil {
+		v, ok := t.Value()
+		if !ok {
+			return nil, false
+		}
+		return v, true
+	}
+
+	v := t.Val()
+	if !v.Valid {
+		return nil, false
+	}
+	return v.String, true
+}
+
+// The ___Col methods associate the given input with a typed DB column and
+// ensure it's compatible with that column type. For example:
+// - IntCol checks to ensure the given value is a valid integer in SQL.
+// - TimeCol checks the value parses as a timestamp and normalizes its layout.
+//
+// Once checked, the value is bound as its native Go type (int, float64, bool,
+// time.Time) so the driver does the type conversion, not the database.
+
+func (f *TableMap) IntCol(name string, input TableMapInput) {
+	f.numberCol(name, kindInt, input, "integer", func(s string) (interface{}, error) {
+		return strconv.Atoi(s)
+	})
+}
+
+// Int64Col is IntCol for BIGINT columns, so 64-bit values survive on 32-bit
+// platforms.
+func (f *TableMap) Int64Col(name string, input TableMapInput) {
+	f.numberCol(name, kindInt64, input, "integer", func(s string) (interface{}, error) {
+		return strconv.ParseInt(s, 10, 64)
+	})
+}
+
+func (f *TableMap) FloatCol(name string, input TableMapInput) {
+	f.numberCol(name, kindFloat, input, "float", func(s string) (interface{}, error) {
+		return strconv.ParseFloat(s, 64)
+	})
+}
+
+// numberCol is typedCol for IntCol, Int64Col and FloatCol. Null input is null
+// without being parsed. A value that's there but doesn't parse is bound as
+// NULL too, but Err reports it, so it isn't mistaken for one that was null.
+func (f *TableMap) numberCol(name string, kind colKind, input TableMapInput, what string, parse func(string) (interface{}, error)) {
+	validate := func(v sql.NullString) error {
+		if !v.Valid {
+			return nil
+		}
+		if _, err := parse(v.String); err != nil {
+			return fmt.Errorf("dbtools: invalid %s %q for column %s", what, v.String, name)
+		}
+		return nil
+	}
+
+	check := func(Dialect) error {
+		return validate(input())
+	}
+
+	inputChecked := func() sql.NullString {
+		v := input()
+		if validate(v) != nil {
+			return sql.NullString{String: "", Valid: false}
+		}
+		return v
+	}
+	f.typedCol(name, kind, inputChecked, check, func(s string) interface{} {
+		n, _ := parse(s)
+		return n
+	})
+}
+
+// decimalRe is what DecimalCol accepts: plain digits with an optional sign
+// and decimal point, no exponent.
+var decimalRe = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+
+// DecimalCol is for NUMERIC/DECIMAL columns, like money, where a float64
+// would lose precision. The value is bound verbatim as a string so the
+// database parses it exactly. It has to be a well-formed decimal with at most
+// scale digits after the point; anything else fails the query (see Err)
+// rather than being rounded.
+func (f *TableMap) DecimalCol(name string, input TableMapInput, scale int) {
+	validate := func(v sql.NullString) error {
+		if !v.Valid {
+			return nil
+		}
+
+		if !decimalRe.MatchString(v.String) {
+			return fmt.Errorf("dbtools: invalid decimal %q for column %s", v.String, name)
+		}
+		if i := strings.IndexByte(v.String, '.'); i >= 0 && len(v.String)-i-1 > scale {
+			return fmt.Errorf("dbtools: decimal %q has more than %d places for column %s", v.String, scale, name)
+		}
+		return nil
+	}
+
+	check := func(Dialect) error {
+		return validate(input())
+	}
+
+	inputChecked := func() sql.NullString {
+		v := input()
+		if validate(v) != nil {
+			return sql.NullString{String: "", Valid: false}
+		}
+		return v
+	}
+	f.addField(name, TableMapField{Val: inputChecked, check: check, kind: kindDecimal, scale: scale})
+}
+
+// EnumCol is a string column that can only hold one of allowed, for
+// databases without enum types. Any other value fails the query (see Err).
+// Reading a value that isn't allowed is an error too; the field can be a
+// string or a named string type like `type Status string`.
+func (f *TableMap) EnumCol(name string, allowed []string, input TableMapInput) {
+	ok := make(map[string]bool, len(allowed))
+	for _, a := range allowed {
+		ok[a] = true
+	}
+
+	validate := func(v sql.NullString) error {
+		if v.Valid && !ok[v.String] {
+			return fmt.Errorf("dbtools: %q isn't an allowed value for column %s", v.String, name)
+		}
+		return nil
+	}
+
+	check := func(Dialect) error {
+		return validate(input())
+	}
+
+	inputChecked := func() sql.NullString {
+		v := input()
+		if validate(v) != nil {
+			return sql.NullString{String: "", Valid: false}
+		}
+		return v
+	}
+
+	read := func(src sql.NullString, dest interface{}) error {
+		if !src.Valid {
+			return nil
+		}
+		if err := validate(src); err != nil {
+			return err
+		}
+		return setRead(dest, src.String)
+	}
+	f.addField(name, TableMapField{Val: inputChecked, read: read, check: check, kind: kindString})
+}
+
+// BoolCol accepts anything strconv.ParseBool does. It binds a bool, and
+// displays as the dialect's boolean literal: "true"/"false" for Postgres,
+// "1"/"0" otherwise.
+func (f *TableMap) BoolCol(name string, input TableMapInput) {
+	validate := func(v sql.NullString) error {
+		if !v.Valid {
+			return nil
+		}
+		if _, err := strconv.ParseBool(v.String); err != nil {
+			return fmt.Errorf("dbtools: invalid bool %q for column %s", v.String, name)
+		}
+		return nil
+	}
+
+	check := func(Dialect) error {
+		return validate(input())
+	}
+
+	inputChecked := func() sql.NullString {
+		v := input()
+		if !v.Valid || validate(v) != nil {
+			return sql.NullString{String: "", Valid: false}
+		}
+		b, _ := strconv.ParseBool(v.String)
+		return sql.NullString{String: strconv.FormatBool(b), Valid: true}
+	}
+	f.typedCol(name, kindBool, inputChecked, check, func(s string) interface{} {
+		b, _ := strconv.ParseBool(s)
+		return b
+	})
+}
+
+// displayVal is the field's value for Print and String. Bools are shown as
+// the literal for the dialect the TableMap has when it's printed, so a Clone
+// given another dialect shows its own.
+func (f *TableMap) displayVal(field TableMapField) sql.NullString {
+	v := field.Val()
+	if field.kind != kindBool || !v.Valid || f.Dialect == DialectPostgres {
+		return v
+	}
+	if v.String == "true" {
+		return sql.NullString{String: "1", Valid: true}
+	}
+	return sql.NullString{String: "0", Valid: true}
+}
+
+// TimeCol binds a time.Time, leaving the storage format to the driver. It's
+// in UTC if the input is FromTime, and keeps fractional seconds.
+func (f *TableMap) TimeCol(name string, input TableMapInput) {
+	f.typedCol(name, kindTime, checkTime(input, time.RFC3339Nano), timeCheck(name, input, time.RFC3339Nano), func(s string) interface{} {
+		t, _ := time.Parse(time.RFC3339Nano, s)
+		return t
+	})
+}
+
+// TimeColWithLayout stores the value as a string formatted with layout, e.g.
+// "2006-01-02" for DATE columns. Values already in RFC3339 (what FromTime
+// produces) are reformatted, so FromTime works with any layout.
+func (f *TableMap) TimeColWithLayout(name string, input TableMapInput, layout string) {
+	f.addField(name, TableMapField{Val: checkTime(input, layout), check: timeCheck(name, input, layout), kind: kindString})
+}
+
+func checkTime(input TableMapInput, layout string) TableMapInput {
+	return func() sql.NullString {
+		v := input()
+		if !v.Valid {
+			return sql.NullString{String: "", Valid: false}
+		}
+
+		t, err := parseTime(v.String, layout)
+		if err != nil {
+			return sql.NullString{String: "", Valid: false}
+		}
+
+		return sql.NullString{String: t.Format(layout), Valid: true}
+	}
+}
+
+// timeCheck is the check for a time column, so input checkTime can't parse
+// (and binds as NULL) is reported by Err.
+func timeCheck(name string, input TableMapInput, layout string) func(Dialect) error {
+	return func(Dialect) error {
+		v := input()
+		if !v.Valid {
+			return nil
+		}
+		if _, err := parseTime(v.String, layout); err != nil {
+			return fmt.Errorf("dbtools: invalid time %q for column %s", v.String, name)
+		}
+		return nil
+	}
+}
+
+// parseTime parses s with layout, or failing that as RFC3339 (what FromTime
+// produces).
+func parseTime(s string, layout string) (time.Time, error) {
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, s)
+	}
+	return t, err
+}
+
+// JSONCol stores v (usually a pointer to a struct or map) as JSON text,
+// marshaling it when the statement is built. A nil v (or nil pointer, map or
+// slice, or a pointer to one) stores NULL. FindInto unmarshals the column back
+// into the matching struct field.
+func (f *TableMap) JSONCol(name string, v interface{}) {
+	input := func() sql.NullString {
+		if isNil(v) {
+			return sql.NullString{String: "", Valid: false}
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return sql.NullString{String: "", Valid: false}
+		}
+		return sql.NullString{String: string(b), Valid: true}
+	}
+
+	check := func(Dialect) error {
+		if isNil(v) {
+			return nil
+		}
+
+		if _, err := json.Marshal(v); err != nil {
+			return fmt.Errorf("dbtools: marshaling JSON column %s: %w", name, err)
+		}
+		return nil
+	}
+
+	read := func(src sql.NullString, dest interface{}) error {
+		if !src.Valid {
+			return nil
+		}
+
+		err := json.Unmarshal([]byte(src.String), dest)
+		if err != nil {
+			return fmt.Errorf("dbtools: unmarshaling JSON column %s: %w", name, err)
+		}
+		return nil
+	}
+
+	f.addField(name, TableMapField{Val: input, read: read, check: check, kind: kindJSON})
+}
+
+// BlobCol passes the bytes through to the driver untouched. Print shows them
+// as hex.
+func (f *TableMap) BlobCol(name string, input TableMapValue) {
+	f.valueCol(name, kindBlob, input, func(v interface{}) string {
+		return fmt.Sprintf("%x", v)
+	})
+}
+
+// ValueCol binds whatever input yields as-is, for types the other ___Col
+// methods don't cover. The driver has to know how to handle it.
+func (f *TableMap) ValueCol(name string, input TableMapValue) {
+	f.valueCol(name, kindUnknown, input, func(v interface{}) string {
+		return fmt.Sprint(v)
+	})
+}
+
+func (f *TableMap) StringCol(name string, input TableMapInput) {
+	m := TableMapField{Val: input, kind: kindString}
+	f.addField(name, m)
+}
+
+// StringColWithDefault is StringCol with a literal default; see Default.
+func (f *TableMap) StringColWithDefault(name string, input TableMapInput, def string) {
+	f.StringCol(name, input)
+	f.Default(name, def)
+}
+
+// addField is where every ___Col method ends up.
+func (f *TableMap) addField(name string, m TableMapField) {
+	if f.checkIdents && !validIdent(name) {
+		if f.mapErr == nil {
+			f.mapErr = fmt.Errorf("dbtools: invalid column name %q", name)
+		}
+		return
+	}
+
+	f.Fields[name] = m
+	f.fieldOrder = append(f.fieldOrder, name)
+}
+
+// typedCol maps a string-based input (already checked) and binds it converted
+// with conv. check, if there is one, is for Err.
+func (f *TableMap) typedCol(name string, kind colKind, input TableMapInput, check func(Dialect) error, conv func(string) interface{}) {
+	value := func() (interface{}, bool) {
+		v := input()
+		if !v.Valid {
+			return nil, false
+		}
+		return conv(v.String), true
+	}
+
+	f.addField(name, TableMapField{Val: input, Value: value, check: check, kind: kind})
+}
+
+// valueCol maps a value-based input, with format standing in for the string
+// Val needs.
+func (f *TableMap) valueCol(name string, kind colKind, input TableMapValue, format func(interface{}) string) {
+	display := func() sql.NullString {
+		v, ok := input()
+		if !ok {
+			return sql.NullString{String: "", Valid: false}
+		}
+		return sql.NullString{String: format(v), Valid: true}
+	}
+
+	f.addField(name, TableMapField{Val: display, Value: input, kind: kind})
+}
+
+// PrimaryKeyCol marks already-mapped columns as the primary key. Update and
+// Delete use it to find the row to change; give more than one name for a
+// composite key.
+func (f *TableMap) PrimaryKeyCol(names ...string) {
+	f.PrimaryKey = names
+}
+
+// VersionCol makes col, an integer column that's already mapped, a version
+// number for optimistic locking. Update only changes the row if its version
+// is still the mapped value, and bumps it with `col=col+1`; if the row has
+// moved on, Update returns ErrStaleData. The mapped value isn't changed, so
+// read the row again (or add one yourself) before the next Update.
+func (f *TableMap) VersionCol(col string) *TableMap {
+	if !f.checkCol("VersionCol", col) {
+		return f
+	}
+
+	f.version = col
+	return f
+}
+
+// versionErr is pkErr for the version column.
+func (f *TableMap) versionErr() error {
+	if f.version == "" {
+		return nil
+	}
+	if _, ok := f.Fields[f.version].arg(); !ok {
+		return fmt.Errorf("dbtools: version column %q is null", f.version)
+	}
+	return nil
+}
+
+// SetNull has the next Create, Update or Upsert write NULL to col, whatever
+// its value. A null field is otherwise left out of an Update, leaving the
+// column as it is, and gets its default on Create. Reset undoes it.
+func (f *TableMap) SetNull(col string) *TableMap {
+	if !f.checkCol("SetNull", col) {
+		return f
+	}
+
+	if f.setNull == nil {
+		f.setNull = make(map[string]bool)
+	}
+	f.setNull[col] = true
+	return f
+}
+
+// Default has Create insert v for col whenever col's value is null, instead
+// of a NULL. v is a literal value, bound like any other; for something the
+// database should work out, like CURRENT_TIMESTAMP, use DefaultSql. Defaults
+// only apply to inserts: a null field still means "leave alone" to Update
+// and "don't filter" to Find.
+func (f *TableMap) Default(col string, v interface{}) *TableMap {
+	if !f.checkCol("Default", col) {
+		return f
+	}
+
+	field := f.Fields[col]
+	field.def = func() (interface{}, bool) { return v, true }
+	field.defSql = ""
+	f.Fields[col] = field
+	return f
+}
+
+// DefaultSql is Default for a SQL expression, like CURRENT_TIMESTAMP, which
+// goes into the INSERT in place of the placeholder. It's not escaped, so it
+// must never come from user input.
+func (f *TableMap) DefaultSql(col string, expr string) *TableMap {
+	if !f.checkCol("DefaultSql", col) {
+		return f
+	}
+
+	field := f.Fields[col]
+	field.def = nil
+	field.defSql = expr
+	f.Fields[col] = field
+	return f
+}
+
+// OmitNullPrimaryKey has Create leave a null primary key out of the INSERT,
+// rather than inserting NULL, so an autoincrement column gets its next value
+// the way the database intends. Rows with an id are inserted with it as
+// usual.
+func (f *TableMap) OmitNullPrimaryKey() *TableMap {
+	f.omitNullPk = true
+	return f
+}
+
+func (f *TableMap) isPrimaryKey(col string) bool {
+	for _, pk := range f.PrimaryKey {
+		if pk == col {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPrimaryKey is whether a primary key is configured and all its columns
+// are mapped.
+func (f *TableMap) hasPrimaryKey() bool {
+	if len(f.PrimaryKey) == 0 {
+		return false
+	}
+	for _, pk := range f.PrimaryKey {
+		if _, ok := f.Fields[pk]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// pkErr checks there's a primary key to identify a row by, and that none of
+// its columns are null.
+func (f *TableMap) pkErr() error {
+	if !f.hasPrimaryKey() {
+		return ErrNoPrimaryKey
+	}
+	for _, pk := range f.PrimaryKey {
+		if _, ok := f.Fields[pk].arg(); !ok {
+			return fmt.Errorf("dbtools: primary key column %q is null", pk)
+		}
+	}
+	return nil
+}
+
+// pkSql is the WHERE condition matching the primary key's current values,
+// appended to args.
+func (f *TableMap) pkSql(args []interface{}) (string, []interface{}) {
+	var where []string
+	for _, pk := range f.PrimaryKey {
+		v, _ := f.Fields[pk].arg()
+		args = append(args, v)
+		where = append(where, f.quote(pk)+"=?")
+	}
+	return strings.Join(where[:], " AND "), args
+}
+
+// The From_____ methods basically take the column and converts it into a
+// sql.NullString.  We'll do nil-handling later in getFieldsHelper.
+
+func FromString(v *string) TableMapInput {
+	return func() sql.NullString {
+		if v == nil {
+			return sql.NullString{String: "", Valid: false}
+		} else {
+			return sql.NullString{String: *v, Valid: true}
+		}
+	}
+}
+
+func FromInt(v *int) TableMapInput {
+	return func() sql.NullString {
+		if v == nil {
+			return sql.NullString{String: "", Valid: false}
+		} else {
+			s := strconv.Itoa(*v)
+			return sql.NullString{String: s, Valid: true}
+		}
+	}
+}
+
+func FromInt64(v *int64) TableMapInput {
+	return func() sql.NullString {
+		if v == nil {
+			return sql.NullString{String: "", Valid: false}
+		} else {
+			s := strconv.FormatInt(*v, 10)
+			return sql.NullString{String: s, Valid: true}
+		}
+	}
+}
+
+func FromFloat(v *float64) TableMapInput {
+	return func() sql.NullString {
+		if v == nil {
+			return sql.NullString{String: "", Valid: false}
+		} else {
+			s := strconv.FormatFloat(*v, 'f', -1, 64)
+			return sql.NullString{String: s, Valid: true}
+		}
+	}
+}
+
+// FromDecimalString is for DecimalCol. The string is passed through as-is and
+// checked by the column.
+func FromDecimalString(v *string) TableMapInput {
+	return FromString(v)
+}
+
+// FromBool doesn't know the dialect, so it emits "true"/"false" and leaves it
+// to BoolCol to convert.
+func FromBool(v *bool) TableMapInput {
+	return func() sql.NullString {
+		if v == nil {
+			return sql.NullString{String: "", Valid: false}
+		} else {
+			s := strconv.FormatBool(*v)
+			return sql.NullString{String: s, Valid: true}
+		}
+	}
+}
+
+// FromTime converts the time to UTC before formatting it, so what's stored
+// doesn't depend on the location of the time.Time it came from, and ToTime
+// reads it back in UTC too. FromTimeKeepLocation is for when the offset
+// itself matters.
+func FromTime(v *time.Time) TableMapInput {
+	return FromTimeWithLayout(v, time.RFC3339Nano)
+}
+
+func FromTimeWithLayout(v *time.Time, layout string) TableMapInput {
+	return fromTime(v, layout, true)
+}
+
+// FromTimeKeepLocation formats the time in its own location. With a layout
+// that has no offset, like TimeColWithLayout's "2006-01-02", that's the local
+// date rather than the UTC one.
+func FromTimeKeepLocation(v *time.Time) TableMapInput {
+	return fromTime(v, time.RFC3339Nano, false)
+}
+
+func fromTime(v *time.Time, layout string, utc bool) TableMapInput {
+	return func() sql.NullString {
+		if v == nil {
+			return sql.NullString{String: "", Valid: false}
+		}
+
+		t := *v
+		if utc {
+			t = t.UTC()
+		}
+		return sql.NullString{String: t.Format(layout), Valid: true}
+	}
+}
+
+// The From____Val methods are for columns that are never null: they take
+// the value itself rather than a pointer, and it's copied, so later changes
+// to the variable aren't seen. Null is the input for a column that always is.
+
+func FromStringVal(v string) TableMapInput {
+	return FromString(&v)
+}
+
+func FromIntVal(v int) TableMapInput {
+	return FromInt(&v)
+}
+
+func FromInt64Val(v int64) TableMapInput {
+	return FromInt64(&v)
+}
+
+func FromFloatVal(v float64) TableMapInput {
+	return FromFloat(&v)
+}
+
+func FromBoolVal(v bool) TableMapInput {
+	return FromBool(&v)
+}
+
+func FromTimeVal(v time.Time) TableMapInput {
+	return FromTime(&v)
+}
+
+func Null() TableMapInput {
+	return func() sql.NullString {
+		return sql.NullString{String: "", Valid: false}
+	}
+}
+
+// FromValue adapts any value for ValueCol. nil (including nil pointers, maps
+// and slices) is null; other pointers are dereferenced when the statement is
+// built.
+func FromValue(v interface{}) TableMapValue {
+	return func() (interface{}, bool) {
+		if isNil(v) {
+			return nil, false
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr {
+			return rv.Elem().Interface(), true
+		}
+		return v, true
+	}
+}
+
+// isNil is true for nil itself and for nil pointers, maps, slices and the
+// like wrapped in an interface{}, following pointers to pointers down.
+func isNil(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Chan, reflect.Func:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// FromBytes treats a nil slice as null; an empty one is an empty blob.
+func FromBytes(v []byte) TableMapValue {
+	return func() (interface{}, bool) {
+		if v == nil {
+			return nil, false
+		} else {
+			return v, true
+		}
+	}
+}
+
+// ReadTransform has FindInto and FindOneInto decode col with read instead of
+// scanning it straight into the struct field, e.g. with ToTime for a
+// timestamp stored as text. Read transforms are to the To_____ funcs what
+// the ___Col methods are to From_____.
+func (f *TableMap) ReadTransform(col string, read TableMapOutput) *TableMap {
+	if !f.checkCol("ReadTransform", col) {
+		return f
+	}
+
+	field := f.Fields[col]
+	field.read = read
+	f.Fields[col] = field
+	return f
+}
+
+// The To_____ funcs make TableMapOutputs for ReadTransform. They leave the
+// field alone for NULL, and handle plain and pointer fields alike.
+
+// ToTime parses the column with layout, for a time.Time field. The time is
+// converted to UTC, whatever offset was stored; layouts without one are
+// taken as UTC already.
+func ToTime(layout string) TableMapOutput {
+	return func(src sql.NullString, dest interface{}) error {
+		if !src.Valid {
+			return nil
+		}
+
+		t, err := time.Parse(layout, src.String)
+		if err != nil {
+			return fmt.Errorf("dbtools: reading time: %w", err)
+		}
+		return setRead(dest, t.UTC())
+	}
+}
+
+// ToBase64 decodes base64 text, for a []byte field.
+func ToBase64() TableMapOutput {
+	return func(src sql.NullString, dest interface{}) error {
+		if !src.Valid {
+			return nil
+		}
+
+		b, err := base64.StdEncoding.DecodeString(src.String)
+		if err != nil {
+			return fmt.Errorf("dbtools: reading base64: %w", err)
+		}
+		return setRead(dest, b)
+	}
+}
+
+// setRead stores v in the field dest points to, allocating it first if the
+// field is itself a pointer. Strings can go into named string types, like a
+// `type Status string`.
+func setRead(dest interface{}, v interface{}) error {
+	field := reflect.ValueOf(dest).Elem()
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.String && field.Kind() == reflect.String {
+		rv = rv.Convert(field.Type())
+	}
+	if !rv.Type().AssignableTo(field.Type()) {
+		return fmt.Errorf("dbtools: can't read %s into a field of type %s", rv.Type(), field.Type())
+	}
+	field.Set(rv)
+	return nil
+}
+
+// Validate adds checks that col's value has to pass before Create, Update or
+// Upsert will write it, like MaxLength for a VARCHAR. A failing check stops
+// the statement with an error naming the column, instead of a truncated
+// write or a constraint violation from the driver. Null values are checked
+// too; use NotNull to reject them.
+func (f *TableMap) Validate(col string, validators ...func(v sql.NullString) error) *TableMap {
+	if !f.checkCol("Validate", col) {
+		return f
+	}
+
+	// copy rather than append in place, in case a Clone shares the slice
+	field := f.Fields[col]
+	field.validators = append(field.validators[:len(field.validators):len(field.validators)], validators...)
+	f.Fields[col] = field
+	return f
+}
+
+// MaxLength rejects values longer than n characters.
+func MaxLength(n int) func(v sql.NullString) error {
+	return func(v sql.NullString) error {
+		if l := utf8.RuneCountInString(v.String); v.Valid && l > n {
+			return fmt.Errorf("%d characters is longer than the maximum of %d", l, n)
+		}
+		return nil
+	}
+}
+
+// NotNull rejects null values.
+func NotNull(v sql.NullString) error {
+	if !v.Valid {
+		return errors.New("can't be null")
+	}
+	return nil
+}
+
+// writeErr is Err plus the Validate checks, for statements that write. A
+// SetNull column is validated as the NULL that will be written.
+func (f *TableMap) writeErr() error {
+	if err := f.Err(); err != nil {
+		return err
+	}
+	for _, colname := range f.fieldOrder {
+		field := f.Fields[colname]
+		if len(field.validators) == 0 {
+			continue
+		}
+
+		v := field.Val()
+		if f.setNull[colname] {
+			v = sql.NullString{}
+		}
+		for _, validate := range field.validators {
+			if err := validate(v); err != nil {
+				return fmt.Errorf("dbtools: invalid %s: %w", colname, err)
+			}
+		}
+	}
+	return nil
+}
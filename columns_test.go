@@ -0,0 +1,99 @@
+package dbtools
+
+import "testing"
+
+func TestIntColNilIsNull(t *testing.T) {
+	tm := NewTableMap(nil, "t")
+	tm.IntCol("n", FromInt(nil))
+
+	if err := tm.Err(); err != nil {
+		t.Errorf("Err with a nil *int = %v, want nil", err)
+	}
+	if v, valid := tm.Fields["n"].arg(); valid {
+		t.Errorf("nil *int binds %v, want NULL", v)
+	}
+}
+
+func TestNumberColsReportMalformedValues(t *testing.T) {
+	cols := map[string]func(tm *TableMap, input TableMapInput){
+		"IntCol":   func(tm *TableMap, input TableMapInput) { tm.IntCol("n", input) },
+		"Int64Col": func(tm *TableMap, input TableMapInput) { tm.Int64Col("n", input) },
+		"FloatCol": func(tm *TableMap, input TableMapInput) { tm.FloatCol("n", input) },
+	}
+
+	for method, col := range cols {
+		tm := NewTableMap(nil, "t")
+		col(tm, FromStringVal("12x"))
+		if err := tm.Err(); err == nil {
+			t.Errorf("%s with %q: Err = nil, want an error", method, "12x")
+		}
+		if v, valid := tm.Fields["n"].arg(); valid {
+			t.Errorf("%s with %q binds %v, want NULL", method, "12x", v)
+		}
+
+		tm = NewTableMap(nil, "t")
+		col(tm, FromStringVal("12"))
+		if err := tm.Err(); err != nil {
+			t.Errorf("%s with %q: Err = %v, want nil", method, "12", err)
+		}
+	}
+}
+
+func TestValidateSeesSetNull(t *testing.T) {
+	id, title := 1, "My Title"
+	tm := (&testMessage{ID: &id, Title: &title}).toTableMap(nil)
+	tm.DryRun = &DryRun{}
+	tm.Validate("title", NotNull).SetNull("title")
+
+	if _, err := tm.Update(); err == nil {
+		t.Error("Update with SetNull on a NotNull column = nil, want an error")
+	}
+	if len(tm.DryRun.Statements) != 0 {
+		t.Errorf("ran %v, want nothing", tm.DryRun.Statements)
+	}
+}
+
+func TestTimeColsReportMalformedValues(t *testing.T) {
+	tm := NewTableMap(nil, "t")
+	tm.TimeCol("at", FromStringVal("not a time"))
+	tm.TimeColWithLayout("on", FromStringVal("2020-01-02"), "2006-01-02")
+	if err := tm.Err(); err == nil {
+		t.Error("TimeCol with \"not a time\": Err = nil, want an error")
+	}
+
+	tm = NewTableMap(nil, "t")
+	tm.TimeColWithLayout("on", FromStringVal("02/01/2020"), "2006-01-02")
+	if err := tm.Err(); err == nil {
+		t.Error("TimeColWithLayout with \"02/01/2020\": Err = nil, want an error")
+	}
+
+	tm = NewTableMap(nil, "t")
+	tm.TimeCol("at", FromStringVal("2020-01-02T03:04:05.5Z"))
+	tm.TimeColWithLayout("on", FromStringVal("2020-01-02T03:04:05Z"), "2006-01-02")
+	if err := tm.Err(); err != nil {
+		t.Errorf("valid times: Err = %v, want nil", err)
+	}
+}
+
+func TestBoolCol(t *testing.T) {
+	tm := NewTableMap(nil, "t")
+	tm.BoolCol("b", FromStringVal("yes"))
+	if err := tm.Err(); err == nil {
+		t.Error("BoolCol with \"yes\": Err = nil, want an error")
+	}
+
+	tm = NewTableMap(nil, "t")
+	tm.BoolCol("b", FromBoolVal(true))
+	if got, want := tm.String(), "TableMap(t){b=1}"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+
+	pg := tm.Clone()
+	pg.SetDialect(DialectPostgres)
+	if got, want := pg.String(), "TableMap(t){b=true}"; got != want {
+		t.Errorf("String on a Postgres Clone = %q, want %q", got, want)
+	}
+	if v, _ := pg.Fields["b"].arg(); v != true {
+		t.Errorf("BoolCol binds %#v, want true", v)
+	}
+}
@@ -0,0 +1,771 @@
+package dbtools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Direction is the sort direction for OrderBy.
+type Direction string
+
+const (
+	ASC  Direction = "ASC"
+	DESC Direction = "DESC"
+)
+
+// Nulls is where OrderByNulls puts NULLs.
+type Nulls string
+
+const (
+	NullsFirst Nulls = "NULLS FIRST"
+	NullsLast  Nulls = "NULLS LAST"
+)
+
+// condition renders one WHERE condition, appending its values to args.
+type condition func(f *TableMap, args []interface{}) (string, []interface{})
+
+// A Condition is a boolean expression for a WHERE clause, built with Cond, In,
+// InSubquery, Between, Like, ILike, IsNull, IsNotNull, Raw, And and Or and
+// added to a TableMap with WhereCond. The columns it uses don't have to be
+// mapped, so you can filter on ones the TableMap never writes, like
+// created_at, or on a joined table's ("authors.name"), but they have to be
+// plain identifiers.
+type Condition struct {
+	cols   []string
+	params []string
+	err    error
+	render condition
+}
+
+// whereOps are the operators Cond accepts. Since op is interpolated into the
+// SQL, anything else is rejected.
+var whereOps = map[string]bool{
+	"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "LIKE": true,
+}
+
+// Cond compares col to value with op, which must be one of =, !=, <, <=, >, >=
+// or LIKE.
+func Cond(col string, op string, value interface{}) Condition {
+	if !whereOps[op] {
+		return Condition{err: fmt.Errorf("dbtools: invalid Where operator %q", op)}
+	}
+
+	return Condition{
+		cols: []string{col},
+		render: func(f *TableMap, args []interface{}) (string, []interface{}) {
+			args = append(args, value)
+			return f.quoteCol(col) + " " + op + " ?", args
+		},
+	}
+}
+
+// In matches col against any of values. An empty list matches nothing.
+func In(col string, values []interface{}) Condition {
+	return Condition{
+		cols: []string{col},
+		render: func(f *TableMap, args []interface{}) (string, []interface{}) {
+			if len(values) == 0 {
+				return "1=0", args
+			}
+
+			var placeholders []string
+			for _, v := range values {
+				args = append(args, v)
+				placeholders = append(placeholders, "?")
+			}
+			return f.quoteCol(col) + " IN (" + strings.Join(placeholders[:], ",") + ")", args
+		},
+	}
+}
+
+// InSubquery matches col against the rows of a hand-written subquery, like
+// `SELECT id FROM banned_users WHERE reason = ?`. Write its placeholders as ?
+// whatever the dialect; its args are bound where the subquery sits. Like Raw,
+// subSql goes into the statement as-is.
+func InSubquery(col string, subSql string, args ...interface{}) Condition {
+	return Condition{
+		cols: []string{col},
+		render: func(f *TableMap, vals []interface{}) (string, []interface{}) {
+			return f.quoteCol(col) + " IN (" + subSql + ")", append(vals, args...)
+		},
+	}
+}
+
+// Between matches col from lo to hi, inclusive at both ends.
+func Between(col string, lo interface{}, hi interface{}) Condition {
+	return Condition{
+		cols: []string{col},
+		render: func(f *TableMap, args []interface{}) (string, []interface{}) {
+			return f.quoteCol(col) + " BETWEEN ? AND ?", append(args, lo, hi)
+		},
+	}
+}
+
+// Like matches col against a LIKE pattern, where % and _ are wildcards and a
+// backslash escapes them. Use EscapeLike on any part of pattern that should
+// be matched literally, like user input. Note SQLite and MySQL's LIKE
+// usually ignore case already.
+func Like(col string, pattern string) Condition {
+	return like(col, pattern, false)
+}
+
+// ILike is Like ignoring case: ILIKE on Postgres, and
+// `LOWER(col) LIKE LOWER(?)` elsewhere.
+func ILike(col string, pattern string) Condition {
+	return like(col, pattern, true)
+}
+
+func like(col string, pattern string, fold bool) Condition {
+	return Condition{
+		cols: []string{col},
+		render: func(f *TableMap, args []interface{}) (string, []interface{}) {
+			// MySQL already escapes with a backslash, and would need it
+			// doubled in the literal
+			escape := ` ESCAPE '\'`
+			if f.Dialect == DialectMySQL {
+				escape = ""
+			}
+
+			col := f.quoteCol(col)
+			switch {
+			case !fold:
+				return col + " LIKE ?" + escape, append(args, pattern)
+			case f.Dialect == DialectPostgres:
+				return col + " ILIKE ?" + escape, append(args, pattern)
+			default:
+				return "LOWER(" + col + ") LIKE LOWER(?)" + escape, append(args, pattern)
+			}
+		},
+	}
+}
+
+// EscapeLike escapes the %, _ and backslashes in s, so a Like pattern
+// matches them literally.
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// IsNull matches rows where col is NULL. A nil value in Cond can't do that,
+// since `col = NULL` is never true.
+func IsNull(col string) Condition {
+	return Condition{
+		cols: []string{col},
+		render: func(f *TableMap, args []interface{}) (string, []interface{}) {
+			return f.quoteCol(col) + " IS NULL", args
+		},
+	}
+}
+
+func IsNotNull(col string) Condition {
+	return Condition{
+		cols: []string{col},
+		render: func(f *TableMap, args []interface{}) (string, []interface{}) {
+			return f.quoteCol(col) + " IS NOT NULL", args
+		},
+	}
+}
+
+// Raw is a condition written by hand, for what the other builders can't do,
+// like `lower(title) = ?`. Use ? for args whatever the dialect, or named
+// parameters like :title whose values come from WithParams. The clause goes
+// into the SQL as-is and isn't checked or escaped, so it must never contain
+// user input; pass that as args.
+func Raw(clause string, args ...interface{}) Condition {
+	clause, names := namedParams(clause)
+	if len(names) == 0 {
+		return Condition{
+			render: func(f *TableMap, vals []interface{}) (string, []interface{}) {
+				return "(" + clause + ")", append(vals, args...)
+			},
+		}
+	}
+
+	var params []string
+	for _, name := range names {
+		if name != "" {
+			params = append(params, name)
+		}
+	}
+
+	return Condition{
+		params: params,
+		render: func(f *TableMap, vals []interface{}) (string, []interface{}) {
+			next := 0
+			for _, name := range names {
+				if name != "" {
+					vals = append(vals, f.params[name])
+				} else if next < len(args) {
+					vals = append(vals, args[next])
+					next++
+				}
+			}
+			return "(" + clause + ")", vals
+		},
+	}
+}
+
+// namedParams replaces each :name in clause with ?, returning the names in
+// the order they appear. If there are any, the ? placeholders already in the
+// clause are listed too, as "", so the two kinds of args can be interleaved.
+// Quoted text and Postgres casts like ::int are left alone.
+func namedParams(clause string) (string, []string) {
+	var b strings.Builder
+	var names []string
+	hasNamed := false
+	var quote byte
+
+	for i := 0; i < len(clause); i++ {
+		c := clause[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '?':
+			names = append(names, "")
+		case c == ':' && i+1 < len(clause) && clause[i+1] == ':':
+			b.WriteString("::")
+			i++
+			continue
+		case c == ':':
+			j := i + 1
+			for j < len(clause) && (clause[j] == '_' || isAlnum(clause[j])) {
+				j++
+			}
+			if j > i+1 && !isDigit(clause[i+1]) {
+				names = append(names, clause[i+1:j])
+				hasNamed = true
+				b.WriteByte('?')
+				i = j - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+
+	if !hasNamed {
+		return clause, nil
+	}
+	return b.String(), names
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func And(conds ...Condition) Condition {
+	return group(" AND ", conds)
+}
+
+func Or(conds ...Condition) Condition {
+	return group(" OR ", conds)
+}
+
+// group joins conds with sep inside parentheses, so it can be nested in
+// another group or ANDed with the rest of the WHERE clause.
+func group(sep string, conds []Condition) Condition {
+	var g Condition
+	for _, c := range conds {
+		if c.err != nil && g.err == nil {
+			g.err = c.err
+		}
+		g.cols = append(g.cols, c.cols...)
+		g.params = append(g.params, c.params...)
+	}
+
+	g.render = func(f *TableMap, args []interface{}) (string, []interface{}) {
+		if len(conds) == 0 {
+			return "1=1", args
+		}
+
+		var parts []string
+		for _, c := range conds {
+			var part string
+			part, args = c.render(f, args)
+			parts = append(parts, part)
+		}
+		return "(" + strings.Join(parts[:], sep) + ")", args
+	}
+	return g
+}
+
+type orderClause struct {
+	col   string
+	dir   Direction
+	nulls Nulls
+}
+
+// sql renders the clause. Postgres has NULLS FIRST/LAST; elsewhere it's
+// emulated by sorting on `col IS NULL` first, which is 1 for NULLs.
+func (o orderClause) sql(f *TableMap) string {
+	col := f.quoteCol(o.col)
+	switch {
+	case o.nulls == "":
+		return col + " " + string(o.dir)
+	case f.Dialect == DialectPostgres:
+		return col + " " + string(o.dir) + " " + string(o.nulls)
+	case o.nulls == NullsFirst:
+		return col + " IS NULL DESC," + col + " " + string(o.dir)
+	default:
+		return col + " IS NULL ASC," + col + " " + string(o.dir)
+	}
+}
+
+// checkCol records an error if col isn't a mapped field. Column names are
+// interpolated into the SQL, so builders only accept ones we know about.
+func (f *TableMap) checkCol(method string, col string) bool {
+	if _, ok := f.Fields[col]; !ok {
+		f.setErr(fmt.Errorf("dbtools: %s on unmapped column %q", method, col))
+		return false
+	}
+	return true
+}
+
+// Where adds a condition like `col >= value` to FindSql and Count, on top of
+// the equality conditions from the non-null fields.
+func (f *TableMap) Where(col string, op string, value interface{}) *TableMap {
+	return f.WhereCond(Cond(col, op, value))
+}
+
+// WhereIn adds `col IN (...)` for the given values. An empty list matches
+// nothing.
+func (f *TableMap) WhereIn(col string, values []interface{}) *TableMap {
+	return f.WhereCond(In(col, values))
+}
+
+// WhereInSubquery adds `col IN (subSql)`; see InSubquery.
+func (f *TableMap) WhereInSubquery(col string, subSql string, args ...interface{}) *TableMap {
+	return f.WhereCond(InSubquery(col, subSql, args...))
+}
+
+// WhereBetween adds `col BETWEEN lo AND hi`, e.g. for a date range.
+func (f *TableMap) WhereBetween(col string, lo interface{}, hi interface{}) *TableMap {
+	return f.WhereCond(Between(col, lo, hi))
+}
+
+// WhereLike matches rows where col contains search, for search boxes. The
+// %, _ and backslashes in search are matched literally; for wildcards, use
+// WhereCond with Like.
+func (f *TableMap) WhereLike(col string, search string) *TableMap {
+	return f.WhereCond(Like(col, "%"+EscapeLike(search)+"%"))
+}
+
+// WhereILike is WhereLike ignoring case.
+func (f *TableMap) WhereILike(col string, search string) *TableMap {
+	return f.WhereCond(ILike(col, "%"+EscapeLike(search)+"%"))
+}
+
+// WhereNull adds `col IS NULL`. Null fields are otherwise left out of the
+// WHERE clause entirely, so this is how to find rows that are actually null.
+func (f *TableMap) WhereNull(col string) *TableMap {
+	return f.WhereCond(IsNull(col))
+}
+
+func (f *TableMap) WhereNotNull(col string) *TableMap {
+	return f.WhereCond(IsNotNull(col))
+}
+
+// RawWhere ANDs a hand-written clause with the other conditions; see Raw. The
+// clause isn't sanitized, so keeping it safe from injection is up to the
+// caller.
+func (f *TableMap) RawWhere(clause string, args ...interface{}) *TableMap {
+	return f.WhereCond(Raw(clause, args...))
+}
+
+// Or adds a single condition that matches if any of conds does, e.g.
+// `(title=? OR body=?)`.
+func (f *TableMap) Or(conds ...Condition) *TableMap {
+	return f.WhereCond(Or(conds...))
+}
+
+// WhereCond ANDs c with the TableMap's other conditions.
+func (f *TableMap) WhereCond(c Condition) *TableMap {
+	if err := f.condErr(c); err != nil {
+		f.setErr(err)
+		return f
+	}
+
+	f.conditions = append(f.conditions, c.render)
+	f.paramNames = append(f.paramNames, c.params...)
+	return f
+}
+
+// WithParams gives the values for named parameters like :title in Raw and
+// RawWhere clauses, and in Having. Calling it again adds to them.
+func (f *TableMap) WithParams(params map[string]interface{}) *TableMap {
+	if f.params == nil {
+		f.params = make(map[string]interface{}, len(params))
+	}
+	for name, v := range params {
+		f.params[name] = v
+	}
+	return f
+}
+
+// paramsErr reports a named parameter WithParams hasn't been given.
+func (f *TableMap) paramsErr() error {
+	for _, name := range f.paramNames {
+		if _, ok := f.params[name]; !ok {
+			return fmt.Errorf("dbtools: no value for parameter :%s", name)
+		}
+	}
+	return nil
+}
+
+// OrderBy sorts FindSql's results by col. Call it again to add tie-breakers.
+// col must be a mapped field, since it's interpolated into the SQL.
+func (f *TableMap) OrderBy(col string, dir Direction) *TableMap {
+	return f.orderByNulls("OrderBy", col, dir, "")
+}
+
+// OrderByNulls is OrderBy with the NULLs put first or last, which databases
+// otherwise disagree on: Postgres treats them as larger than any value,
+// SQLite and MySQL as smaller.
+func (f *TableMap) OrderByNulls(col string, dir Direction, nulls Nulls) *TableMap {
+	if nulls != NullsFirst && nulls != NullsLast {
+		f.setErr(fmt.Errorf("dbtools: invalid nulls placement %q", nulls))
+		return f
+	}
+	return f.orderByNulls("OrderByNulls", col, dir, nulls)
+}
+
+func (f *TableMap) orderByNulls(method string, col string, dir Direction, nulls Nulls) *TableMap {
+	if !f.checkCol(method, col) {
+		return f
+	}
+	if dir != ASC && dir != DESC {
+		f.setErr(fmt.Errorf("dbtools: invalid sort direction %q", dir))
+		return f
+	}
+
+	f.orderBy = append(f.orderBy, orderClause{col: col, dir: dir, nulls: nulls})
+	return f
+}
+
+// Select restricts FindSql to cols, in that order, instead of every mapped
+// field. A Find parser gets just those columns.
+func (f *TableMap) Select(cols ...string) *TableMap {
+	for _, col := range cols {
+		if !f.checkCol("Select", col) {
+			return f
+		}
+	}
+
+	f.selected = cols
+	return f
+}
+
+// Distinct has FindSql skip duplicate rows with SELECT DISTINCT. With
+// Select("author") it's the unique values of one column.
+func (f *TableMap) Distinct() *TableMap {
+	f.distinct = true
+	return f
+}
+
+// selectCols is what FindSql selects: the Select columns if there are any,
+// otherwise all of them.
+func (f *TableMap) selectCols() []string {
+	if len(f.selected) > 0 {
+		return f.selected
+	}
+	return f.fieldOrder
+}
+
+// Join adds `JOIN table ON on` to FindSql, Count and Exists, e.g.
+// Join("authors", "authors.id = messages.author_id"). Both go into the SQL
+// as-is, so like Raw they must never contain user input. Once there's a join,
+// the TableMap's own columns are qualified with its table name so they can't
+// be ambiguous; use SelectJoined to fetch columns from the joined tables.
+func (f *TableMap) Join(table string, on string) *TableMap {
+	f.joins = append(f.joins, " JOIN "+table+" ON "+on)
+	return f
+}
+
+// LeftJoin is Join with LEFT JOIN, keeping rows that have no match.
+func (f *TableMap) LeftJoin(table string, on string) *TableMap {
+	f.joins = append(f.joins, " LEFT JOIN "+table+" ON "+on)
+	return f
+}
+
+// SelectJoined adds columns from joined tables to FindSql, after the
+// TableMap's own, written qualified like "authors.name". Each is selected AS
+// its qualified name, so FindInto puts it in the struct field tagged with the
+// same name (`db:"authors.name"`).
+func (f *TableMap) SelectJoined(cols ...string) *TableMap {
+	for _, col := range cols {
+		f.extraCols = append(f.extraCols, extraCol{expr: col, quoted: true, alias: col, name: col})
+	}
+	return f
+}
+
+// SelectExpr adds expressions like `COUNT(*)` to FindSql after the columns,
+// for use with GroupBy. They go into the SQL as-is, so like Raw they must
+// never contain user input.
+func (f *TableMap) SelectExpr(exprs ...string) *TableMap {
+	for _, expr := range exprs {
+		f.extraCols = append(f.extraCols, extraCol{expr: expr, name: expr})
+	}
+	return f
+}
+
+// SelectAs adds `expr AS alias` to FindSql, naming a joined or computed
+// column, e.g. SelectAs("authors.name", "author_name") or
+// SelectAs("COUNT(*)", "n"). FindInto puts it in the field for alias. Like
+// SelectExpr, expr goes into the SQL as-is; alias must be a plain identifier.
+func (f *TableMap) SelectAs(expr string, alias string) *TableMap {
+	if !validIdent(alias) {
+		f.setErr(fmt.Errorf("dbtools: invalid column alias %q", alias))
+		return f
+	}
+
+	f.extraCols = append(f.extraCols, extraCol{expr: expr, alias: alias, name: alias})
+	return f
+}
+
+// extraCol is a SelectJoined, SelectExpr or SelectAs column: what selects it,
+// quoted or as-is, its alias if any, and the name it's scanned by. It's
+// quoted in sql, so the dialect can still change.
+type extraCol struct {
+	expr   string
+	quoted bool
+	alias  string
+	name   string
+}
+
+func (c extraCol) sql(f *TableMap) string {
+	s := c.expr
+	if c.quoted {
+		s = f.quote(c.expr)
+	}
+	if c.alias != "" {
+		s += " AS " + f.quoteName(c.alias)
+	}
+	return s
+}
+
+// quoteCol quotes one of the TableMap's own columns for a SELECT or WHERE,
+// qualifying it with the table name when there are joins. Columns that are
+// already qualified are left as they are.
+func (f *TableMap) quoteCol(col string) string {
+	if len(f.joins) == 0 || strings.Contains(col, ".") {
+		return f.quote(col)
+	}
+	return f.quote(f.TableName) + "." + f.quote(col)
+}
+
+// fromSql is the table to select from, with its joins.
+func (f *TableMap) fromSql() string {
+	return f.quote(f.TableName) + strings.Join(f.joins, "")
+}
+
+// findCols are the columns FindSql returns, in order: selectCols, then the
+// SelectJoined, SelectExpr and SelectAs ones.
+func (f *TableMap) findCols() []string {
+	if len(f.extraCols) == 0 {
+		return f.selectCols()
+	}
+
+	cols := append([]string(nil), f.selectCols()...)
+	for _, c := range f.extraCols {
+		cols = append(cols, c.name)
+	}
+	return cols
+}
+
+// GroupBy groups FindSql's rows by cols, for aggregates added with
+// SelectExpr, e.g. Select("author_id").SelectExpr("COUNT(*)").
+// GroupBy("author_id"). Like Where, cols don't have to be mapped but must be
+// plain identifiers.
+func (f *TableMap) GroupBy(cols ...string) *TableMap {
+	for _, col := range cols {
+		if _, ok := f.Fields[col]; !ok && !validColRef(col) {
+			f.setErr(fmt.Errorf("dbtools: GroupBy on invalid column %q", col))
+			return f
+		}
+	}
+
+	f.groupBy = append(f.groupBy, cols...)
+	return f
+}
+
+// Having filters the groups from GroupBy with a hand-written clause like
+// `COUNT(*) > ?`. It's ANDed with any other Having clauses and isn't
+// sanitized; see Raw.
+func (f *TableMap) Having(clause string, args ...interface{}) *TableMap {
+	c := Raw(clause, args...)
+	f.having = append(f.having, c.render)
+	f.paramNames = append(f.paramNames, c.params...)
+	return f
+}
+
+// groupSql is the GROUP BY and HAVING clauses, appending the HAVING args.
+func (f *TableMap) groupSql(args []interface{}) (string, []interface{}) {
+	var sql string
+	if len(f.groupBy) > 0 {
+		var cols []string
+		for _, col := range f.groupBy {
+			cols = append(cols, f.quoteCol(col))
+		}
+		sql = " GROUP BY " + strings.Join(cols[:], ",")
+	}
+
+	var having []string
+	for _, c := range f.having {
+		var cond string
+		cond, args = c(f, args)
+		having = append(having, cond)
+	}
+	if len(having) > 0 {
+		sql += " HAVING " + strings.Join(having[:], " AND ")
+	}
+	return sql, args
+}
+
+// ForUpdate locks the rows FindSql matches with `FOR UPDATE`, until the end
+// of the transaction, for read-modify-write with WithTx. It's for Postgres
+// and MySQL; SQLite has no row locks (it locks the whole database on write)
+// so there it's an error, reported when the query runs.
+func (f *TableMap) ForUpdate() *TableMap {
+	f.forUpdate = true
+	return f
+}
+
+// Returning has Create, Update and Upsert return cols from the rows they
+// write, with `RETURNING col1,col2`. Use CreateReturning or UpdateReturning
+// to read them. Postgres and SQLite (3.35+) support it; MySQL doesn't, and
+// gets ErrReturningUnsupported rather than the clause being dropped.
+func (f *TableMap) Returning(cols ...string) *TableMap {
+	for _, col := range cols {
+		if !f.checkCol("Returning", col) {
+			return f
+		}
+	}
+
+	f.returning = cols
+	return f
+}
+
+// dialectErr is for the clauses that depend on the dialect. They're checked
+// when the statement runs rather than when they're added, so SetDialect can
+// still come after.
+func (f *TableMap) dialectErr() error {
+	if f.forUpdate && f.Dialect == DialectSQLite {
+		return errors.New("dbtools: SQLite doesn't support FOR UPDATE")
+	}
+	if len(f.returning) > 0 && f.Dialect == DialectMySQL {
+		return ErrReturningUnsupported
+	}
+	return nil
+}
+
+func (f *TableMap) returningSql() string {
+	if len(f.returning) == 0 {
+		return ""
+	}
+	return " RETURNING " + strings.Join(f.quoteAll(f.returning), ",")
+}
+
+// Limit caps the number of rows FindSql returns. Zero means no limit.
+func (f *TableMap) Limit(n int) *TableMap {
+	f.limit = n
+	return f
+}
+
+func (f *TableMap) Offset(n int) *TableMap {
+	f.offset = n
+	return f
+}
+
+// condsErr returns the first error in conds, including column names that are
+// neither mapped nor plain (optionally table-qualified) identifiers, and
+// named parameters WithParams hasn't been given.
+func (f *TableMap) condsErr(conds []Condition) error {
+	for _, c := range conds {
+		if err := f.condErr(c); err != nil {
+			return err
+		}
+		for _, name := range c.params {
+			if _, ok := f.params[name]; !ok {
+				return fmt.Errorf("dbtools: no value for parameter :%s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// condErr is condsErr for one condition without the parameter check, for
+// WhereCond: WithParams may still come after it, so paramsErr checks later.
+func (f *TableMap) condErr(c Condition) error {
+	if c.err != nil {
+		return c.err
+	}
+	for _, col := range c.cols {
+		if _, ok := f.Fields[col]; !ok && !validColRef(col) {
+			return fmt.Errorf("dbtools: Where on invalid column %q", col)
+		}
+	}
+	return nil
+}
+
+// validColRef is true for "col" and "table.col" where each part is a valid
+// identifier.
+func validColRef(col string) bool {
+	for _, part := range strings.Split(col, ".") {
+		if !validIdent(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// condsSql ANDs conds into a WHERE clause, appending their values to args.
+// It's empty if there are no conds.
+func (f *TableMap) condsSql(conds []Condition, args []interface{}) (string, []interface{}) {
+	var where []string
+	for _, c := range conds {
+		var cond string
+		cond, args = c.render(f, args)
+		where = append(where, cond)
+	}
+
+	if len(where) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(where[:], " AND "), args
+}
+
+// whereSql ANDs every non-null field (unless condsOnly is set) with the
+// conditions added with Where. It's empty when there aren't any, so the
+// statement applies to the whole table.
+func (f *TableMap) whereSql() (string, []interface{}) {
+	var where []string
+	var vals []interface{}
+	if !f.condsOnly {
+		var cols, placeholders []string
+		cols, placeholders, vals = f.GetFieldsWithoutNulls()
+		for i, col := range cols {
+			cond := f.quoteCol(col) + "=" + placeholders[i]
+			where = append(where, cond)
+		}
+	}
+
+	for _, c := range f.conditions {
+		var cond string
+		cond, vals = c(f, vals)
+		where = append(where, cond)
+	}
+
+	if len(where) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(where[:], " AND "), vals
+}
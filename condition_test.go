@@ -0,0 +1,48 @@
+package dbtools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamedParams(t *testing.T) {
+	tests := []struct {
+		clause string
+		want   string
+		names  []string
+	}{
+		{`title = :title`, `title = ?`, []string{"title"}},
+		{`id::int = :id`, `id::int = ?`, []string{"id"}},
+		{`:a::text = body`, `?::text = body`, []string{"a"}},
+		{`created_at > :since::timestamptz`, `created_at > ?::timestamptz`, []string{"since"}},
+		{`title = ':title'`, `title = ':title'`, nil},
+		{`title = ':title' AND body = :body`, `title = ':title' AND body = ?`, []string{"body"}},
+		{`"a:b" = :b`, `"a:b" = ?`, []string{"b"}},
+		{`a = ? AND b = :b AND c = ?`, `a = ? AND b = ? AND c = ?`, []string{"", "b", ""}},
+		{`a = '?' AND b = :b`, `a = '?' AND b = ?`, []string{"b"}},
+		{`a = ? AND b = ?`, `a = ? AND b = ?`, nil},
+		{`a = :1`, `a = :1`, nil},
+		{`a = :`, `a = :`, nil},
+	}
+	for _, tt := range tests {
+		got, names := namedParams(tt.clause)
+		if got != tt.want || !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("namedParams(%q) = %q, %q; want %q, %q", tt.clause, got, names, tt.want, tt.names)
+		}
+	}
+}
+
+func TestRawMixesNamedAndPositionalArgs(t *testing.T) {
+	tm := (&testMessage{}).toTableMap(nil)
+	tm.SetDialect(DialectPostgres)
+	tm.WhereCond(Raw(`id::int > ? AND title = :title AND body <> ?`, 1, "x")).
+		WithParams(map[string]interface{}{"title": "hi"})
+
+	query, vals := tm.FindSql()
+	if want := `SELECT "id","title","body" FROM "messages" WHERE (id::int > $1 AND title = $2 AND body <> $3)`; query != want {
+		t.Errorf("FindSql = %q, want %q", query, want)
+	}
+	if want := []interface{}{1, "hi", "x"}; !reflect.DeepEqual(vals, want) {
+		t.Errorf("FindSql args = %v, want %v", vals, want)
+	}
+}
@@ -0,0 +1,57 @@
+package dbtools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"io"
+)
+
+// ExportCSV runs the Find query and writes the rows to w as CSV, after a
+// header of the column names. NULLs are empty fields. Rows are written as
+// they're read, so it's fine for tables that don't fit in memory.
+func (f *TableMap) ExportCSV(w io.Writer) error {
+	return f.ExportCSVContext(context.Background(), w)
+}
+
+func (f *TableMap) ExportCSVContext(ctx context.Context, w io.Writer) error {
+	rows, query, err := f.findRows(ctx)
+	if err == ErrDryRun {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols := f.findCols()
+	out := csv.NewWriter(w)
+	if err := out.Write(cols); err != nil {
+		return err
+	}
+
+	vals := make([]sql.NullString, len(cols))
+	targets := make([]interface{}, len(cols))
+	for i := range vals {
+		targets[i] = &vals[i]
+	}
+	record := make([]string, len(cols))
+
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+		for i, v := range vals {
+			record[i] = v.String
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return f.wrapErr("find", query, err)
+	}
+
+	out.Flush()
+	return out.Error()
+}
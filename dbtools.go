@@ -1,115 +1,151 @@
-package main
+// Package dbtools maps Go values onto table rows with a TableMap, and builds
+// and runs the SQL to create, find, update and delete them.
+package dbtools
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// ErrNoPrimaryKey is returned by operations that need to identify a single
+// row (like Update) when the TableMap has no primary key column.
+var ErrNoPrimaryKey = errors.New("dbtools: no primary key column configured")
+
+// ErrNoConditions is returned by Delete, DeleteWhere and UpdateWhere when
+// there's nothing to build a WHERE clause from, rather than changing every row
+// in the table.
+var ErrNoConditions = errors.New("dbtools: refusing to run without conditions")
+
+// ErrReturningUnsupported is returned when Returning is used with MySQL,
+// which has no RETURNING clause.
+var ErrReturningUnsupported = errors.New("dbtools: RETURNING isn't supported by MySQL")
+
+// ErrStaleData is returned by Update on a TableMap with a VersionCol when no
+// row had the expected version, meaning someone else updated it first.
+var ErrStaleData = errors.New("dbtools: row was changed or deleted since it was read")
 
-	"github.com/davecgh/go-spew/spew"
-	_ "github.com/davecgh/go-spew/spew"
-	_ "github.com/mattn/go-sqlite3"
+// ErrNoFields is returned when an UPDATE would have nothing to SET.
+var ErrNoFields = errors.New("dbtools: no non-null fields to update")
+
+// Dialect selects the SQL flavor the TableMap generates. The zero value is
+// SQLite.
+type Dialect int
+
+const (
+	DialectSQLite Dialect = iota
+	DialectPostgres
+	DialectMySQL
 )
 
-const TABLE_NAME = "messages"
+// Logger is called with each statement a TableMap runs, its args, and how
+// long the database took to run it. For Find, that's the time until the first
+// row is ready, not the time to read them all.
+type Logger func(query string, args []interface{}, elapsed time.Duration)
+
+// Observer is told about each statement a TableMap runs once it's done, for
+// metrics: op is the operation, like "create" or "find", and err is nil if it
+// succeeded. Set a TableMap's Observer field to use one. As with Logger, a
+// find's duration is until the first row is ready. Single-row statements,
+// like Count, are reported once the row is scanned, with the Scan's error.
+type Observer interface {
+	ObserveQuery(op string, table string, duration time.Duration, err error)
+}
 
-// user code - the only thing the library user needs to write
-type Message struct {
-	ID    *int
-	Title *string
-	Body  *string
+// QueryError is what a TableMap returns when the database fails a statement.
+// It has the SQL but deliberately not the args, which might hold secrets. Use
+// errors.Is or errors.As to get at the underlying driver error.
+type QueryError struct {
+	Op    string
+	Table string
+	SQL   string
+	Err   error
 }
 
-func (m *Message) toTableMap(db *sql.DB) *TableMap {
-	tm := NewTableMap(db, TABLE_NAME)
-	tm.IntCol("id", FromInt(m.ID))
-	tm.StringCol("title", FromString(m.Title))
-	tm.StringCol("body", FromString(m.Body))
-	return tm
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("dbtools: %s on %s: %v", e.Op, e.Table, e.Err)
 }
 
-// end user code
-
-func main() {
-	db, err := sql.Open("sqlite3", "./foo.db")
-	checkErr(err)
-	defer db.Close()
-
-	prepareDB(db)
-
-	id := 1
-	title := "My Title"
-	body := "My Body"
-	msg := Message{ID: &id, Title: &title, Body: &body}
-
-	tm := msg.toTableMap(db)
-	tm.Print()
-
-	_, err = tm.Create()
-	checkErr(err)
-	fmt.Println("-----------")
-
-	msg = Message{ID: &id}
-	tm = msg.toTableMap(db)
-
-	// Setting the struct from the database is still pretty clunky. The
-	// alternatives are:
-	//
-	// 1. store pointers from the struct in a closure, then set
-	// value of the pointer; the problem with this is if the pointers are nil,
-	// you can't re-set the underlying value and still have it associated with
-	// the struct.
-	//
-	// 2. the method below, where I've at least abstracted away the boilerplate
-	// and the user just provides a function to process sql.Rows
-	//
-	// 3. use reflection. the performance penalty probably doesn't matter, and
-	// we can store the correct setters in a closure to prevent bugs. But it's
-	// still basically "unsafe" code.
-	//
-	// example of setting a field with reflection
-	// val := reflect.ValueOf(&n)
-	// (val.Elem()).FieldByName("title").SetString("My Title")
-	//
-	// 4. I'm sure there's also an approach using type assertions (rows.Scan into
-	// an appropriately-sized array of interface{}), and like reflection we could
-	// store the type assertion in the appropriately-typed closure. But then we
-	// still have the null pointer problem.
-
-	var fetchedMessages []Message
-	err = tm.Find(func(rows *sql.Rows) error {
-		var id int
-		var title string
-		var body string
-
-		err = rows.Scan(&id, &title, &body)
-		if err != nil {
-			return err
-		}
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
 
-		fetchedMessages = append(
-			fetchedMessages,
-			Message{ID: &id, Title: &title, Body: &body})
+// ErrDryRun is what reads return in a dry run (see DryRun), since there are
+// no rows to give back. Find and FindInto are the exception: they just find
+// nothing.
+var ErrDryRun = errors.New("dbtools: dry run, nothing was read")
+
+// DryRun collects the statements a TableMap would have run. Set a TableMap's
+// DryRun field to one and its queries are recorded here instead of going to
+// the database, which can then be nil; handy for testing a mapping without
+// SQLite. Writes report no rows affected.
+type DryRun struct {
+	Statements []Statement
+}
 
-		return nil
-	})
-	checkErr(err)
-	spew.Dump(fetchedMessages)
+// Statement is one query DryRun recorded, with its args.
+type Statement struct {
+	SQL  string
+	Args []interface{}
 }
 
-func checkErr(err error) {
-	if err != nil {
-		panic(err)
-	}
+func (d *DryRun) record(query string, args []interface{}) {
+	d.Statements = append(d.Statements, Statement{SQL: query, Args: args})
 }
 
-// library code
+// Executor runs statements for a TableMap. Both *sql.DB and *sql.Tx
+// satisfy it.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
 
 type TableMap struct {
-	DB         *sql.DB
-	TableName  string
-	Fields     map[string]TableMapField
-	fieldOrder []string
+	DB          *sql.DB
+	TableName   string
+	Dialect     Dialect
+	Logger      Logger
+	DryRun      *DryRun
+	Observer    Observer
+	SnakeCase   bool
+	PrimaryKey  []string
+	Fields      map[string]TableMapField
+	fieldOrder  []string
+	tx          *sql.Tx
+	selected    []string
+	conditions  []condition
+	condsOnly   bool
+	orderBy     []orderClause
+	joins       []string
+	extraCols   []extraCol
+	params      map[string]interface{}
+	paramNames  []string
+	setNull     map[string]bool
+	groupBy     []string
+	having      []condition
+	returning   []string
+	limit       int
+	offset      int
+	forUpdate   bool
+	skipLocked  bool
+	distinct    bool
+	err         error
+	mapErr      error
+	checkIdents bool
+	omitNullPk  bool
+	softDelete  string
+	withDeleted bool
+	version     string
+	setId       func(id int64)
 }
 
 func NewTableMap(db *sql.DB, tableName string) *TableMap {
@@ -117,182 +153,488 @@ func NewTableMap(db *sql.DB, tableName string) *TableMap {
 	return &tm
 }
 
-func (f *TableMap) Print() {
-	fields := f.Fields
-	for colname, slfield := range fields {
-		v := slfield.Val()
+// identRe is what NewTableMapChecked allows for table and column names.
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 
-		var output string
-		if v.Valid {
-			output = v.String
-		} else {
-			output = "<null>"
+func validIdent(name string) bool {
+	return identRe.MatchString(name)
+}
+
+// NewTableMapChecked is NewTableMap for when the table or column names might
+// come from user input. It rejects a tableName that isn't a plain identifier,
+// and columns added later with invalid names make the TableMap's queries
+// fail (see Err).
+func NewTableMapChecked(db *sql.DB, tableName string) (*TableMap, error) {
+	if !validIdent(tableName) {
+		return nil, fmt.Errorf("dbtools: invalid table name %q", tableName)
+	}
+
+	tm := NewTableMap(db, tableName)
+	tm.checkIdents = true
+	return tm, nil
+}
+
+// WithTx returns a copy of the TableMap that runs its statements inside tx.
+// It's a Clone, so adding conditions to the copy doesn't touch the original.
+func (f *TableMap) WithTx(tx *sql.Tx) *TableMap {
+	tm := f.Clone()
+	tm.tx = tx
+	return tm
+}
+
+// RunAll runs fns one after another in a single transaction on db, each
+// given the *sql.Tx to use (with WithTx), and commits if they all succeed. It
+// stops and rolls back at the first error, and returns it.
+func RunAll(db *sql.DB, fns ...func(tx *sql.Tx) error) error {
+	return RunAllContext(context.Background(), db, fns...)
+}
+
+func RunAllContext(ctx context.Context, db *sql.DB, fns ...func(tx *sql.Tx) error) error {
+	return inTx(ctx, db, func(tx *sql.Tx) error {
+		for _, fn := range fns {
+			if err := fn(tx); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+}
 
-		fmt.Printf("%s : %s\n", colname, output)
+// WithTransaction runs fn in a transaction on the TableMap's DB, passing it a
+// copy of the TableMap bound to the transaction (see WithTx). It commits if
+// fn returns nil, and otherwise rolls back and returns fn's error. A panic in
+// fn rolls back too, and carries on up. In a dry run there's no transaction:
+// fn gets a copy of the TableMap, whose statements are recorded as usual.
+func (f *TableMap) WithTransaction(ctx context.Context, fn func(txMap *TableMap) error) error {
+	if f.DryRun != nil {
+		return fn(f.Clone())
 	}
+	return inTx(ctx, f.DB, func(tx *sql.Tx) error {
+		return fn(f.WithTx(tx))
+	})
 }
 
-func (f *TableMap) CreateSql() (string, []interface{}) {
-	cols, placeholders, vals := f.GetFields()
+// inTx is the begin/commit/rollback behind RunAll and WithTransaction. The
+// rollback's own error is dropped in favour of the one that caused it.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if db == nil {
+		return errors.New("dbtools: no DB to begin a transaction on")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
 
-	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)\n",
-		f.TableName,
-		strings.Join(cols[:], ","),
-		strings.Join(placeholders[:], ","))
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
 
-	return sql, vals
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
-func (f *TableMap) Create() (sql.Result, error) {
-	sql, vals := f.CreateSql()
-	r, err := f.DB.Exec(sql, vals...)
-	return r, err
+// Ping checks the database is reachable, e.g. before a service starts taking
+// traffic. The error names the driver, like "*sqlite3.SQLiteDriver".
+func (f *TableMap) Ping(ctx context.Context) error {
+	if f.DB == nil {
+		return errors.New("dbtools: no DB to ping")
+	}
+	if err := f.DB.PingContext(ctx); err != nil {
+		return fmt.Errorf("dbtools: ping %T: %w", f.DB.Driver(), err)
+	}
+	return nil
 }
 
-func (f *TableMap) FindSql() (string, []interface{}) {
-	allcols, _, _ := f.GetFields()
-	cols, placeholders, vals := f.GetFieldsWithoutNulls()
+// Clone returns a copy of the TableMap that can be changed, with more Where
+// conditions say, without affecting the original. It shares the DB (and the
+// transaction, if any) but copies the field mappings and the query state.
+func (f *TableMap) Clone() *TableMap {
+	tm := *f
 
-	var where []string
-	for i, col := range cols {
-		cond := col + "=" + placeholders[i]
-		where = append(where, cond)
+	tm.Fields = make(map[string]TableMapField, len(f.Fields))
+	for name, field := range f.Fields {
+		tm.Fields[name] = field
+	}
+	tm.fieldOrder = append([]string(nil), f.fieldOrder...)
+	tm.PrimaryKey = append([]string(nil), f.PrimaryKey...)
+	tm.selected = append([]string(nil), f.selected...)
+	tm.conditions = append([]condition(nil), f.conditions...)
+	tm.orderBy = append([]orderClause(nil), f.orderBy...)
+	tm.joins = append([]string(nil), f.joins...)
+	tm.extraCols = append([]extraCol(nil), f.extraCols...)
+	tm.groupBy = append([]string(nil), f.groupBy...)
+	tm.having = append([]condition(nil), f.having...)
+	tm.paramNames = append([]string(nil), f.paramNames...)
+	tm.params = make(map[string]interface{}, len(f.params))
+	for name, v := range f.params {
+		tm.params[name] = v
 	}
+	tm.setNull = make(map[string]bool, len(f.setNull))
+	for col := range f.setNull {
+		tm.setNull[col] = true
+	}
+	tm.returning = append([]string(nil), f.returning...)
+	return &tm
+}
 
-	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
-		strings.Join(allcols[:], ","),
-		f.TableName,
-		strings.Join(where[:], ","))
-	return sql, vals
+// Reset clears what's been added with Where, Select, Join, GroupBy, OrderBy,
+// Limit, Distinct, Returning, ForUpdate, WithDeleted, WithParams, SetNull and
+// the like, along with any error from them, so the TableMap can be reused for
+// the next query. The fields, DB and dialect stay as they are.
+func (f *TableMap) Reset() *TableMap {
+	f.selected = nil
+	f.conditions = nil
+	f.orderBy = nil
+	f.joins = nil
+	f.extraCols = nil
+	f.groupBy = nil
+	f.having = nil
+	f.params = nil
+	f.paramNames = nil
+	f.setNull = nil
+	f.returning = nil
+	f.limit = 0
+	f.offset = 0
+	f.forUpdate = false
+	f.skipLocked = false
+	f.distinct = false
+	f.withDeleted = false
+	f.err = nil
+	return f
 }
 
-func (f *TableMap) Find(parser func(rows *sql.Rows) error) error {
-	sql, vals := f.FindSql()
+// executor is the transaction if the TableMap is bound to one, the DB
+// otherwise.
+func (f *TableMap) executor() Executor {
+	if f.tx != nil {
+		return f.tx
+	}
+	return f.DB
+}
 
-	rows, err := f.DB.Query(sql, vals...)
-	if err != nil {
-		return err
+// exec, query and queryRow are how every statement gets run, so there's one
+// place to hook in logging, the Observer, error wrapping and dry runs. op
+// names the operation ("create", "find", ...) for QueryError. queryRow can't
+// wrap errors since they only show up on Scan; see observedRow. Its error is
+// only ever ErrDryRun or one from checkArgs.
+func (f *TableMap) exec(ctx context.Context, op string, query string, args []interface{}) (sql.Result, error) {
+	if err := checkArgs(f.Dialect, op, query, args); err != nil {
+		return nil, err
+	}
+	if f.DryRun != nil {
+		f.DryRun.record(query, args)
+		return driver.RowsAffected(0), nil
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		err := parser(rows)
-		if err != nil {
-			return err
-		}
+	start := time.Now()
+	r, err := f.executor().ExecContext(ctx, query, args...)
+	f.log(query, args, start)
+	err = f.wrapErr(op, query, err)
+	f.observe(op, start, err)
+	return r, err
+}
+
+func (f *TableMap) query(ctx context.Context, op string, query string, args []interface{}) (*sql.Rows, error) {
+	if err := checkArgs(f.Dialect, op, query, args); err != nil {
+		return nil, err
+	}
+	if f.DryRun != nil {
+		f.DryRun.record(query, args)
+		return nil, ErrDryRun
 	}
 
-	return nil
+	start := time.Now()
+	rows, err := f.executor().QueryContext(ctx, query, args...)
+	f.log(query, args, start)
+	err = f.wrapErr(op, query, err)
+	f.observe(op, start, err)
+	return rows, err
+}
+
+func (f *TableMap) queryRow(ctx context.Context, op string, query string, args []interface{}) (*observedRow, error) {
+	if err := checkArgs(f.Dialect, op, query, args); err != nil {
+		return nil, err
+	}
+	if f.DryRun != nil {
+		f.DryRun.record(query, args)
+		return nil, ErrDryRun
+	}
+
+	start := time.Now()
+	row := f.executor().QueryRowContext(ctx, query, args...)
+	f.log(query, args, start)
+	return &observedRow{Row: row, f: f, op: op, query: query, start: start}, nil
 }
 
-func (f *TableMap) GetFieldsWithoutNulls() ([]string, []string, []interface{}) {
-	return f.getFieldsHelper(false)
+// observedRow is the *sql.Row from queryRow. Its errors only show up on Scan,
+// so that's when the Observer hears about the statement: Scan wraps the error
+// and reports it, and callers that hand Row to a parser report what the
+// parser returns with done. sql.ErrNoRows counts as success, as in wrapErr.
+type observedRow struct {
+	*sql.Row
+	f     *TableMap
+	op    string
+	query string
+	start time.Time
 }
 
-func (f *TableMap) GetFields() ([]string, []string, []interface{}) {
-	return f.getFieldsHelper(true)
+func (r *observedRow) Scan(dest ...interface{}) error {
+	return r.done(r.f.wrapErr(r.op, r.query, r.Row.Scan(dest...)))
 }
 
-func (f *TableMap) getFieldsHelper(inclnull bool) ([]string, []string, []interface{}) {
-	var cols []string
-	var vals []interface{}
+func (r *observedRow) done(err error) error {
+	if err == sql.ErrNoRows {
+		r.f.observe(r.op, r.start, nil)
+	} else {
+		r.f.observe(r.op, r.start, err)
+	}
+	return err
+}
 
-	for _, fieldName := range f.fieldOrder {
-		v := f.Fields[fieldName].Val()
+// wrapErr leaves sql.ErrNoRows alone, since it isn't really a failure and
+// callers compare against it directly.
+func (f *TableMap) wrapErr(op string, query string, err error) error {
+	if err == nil || err == sql.ErrNoRows {
+		return err
+	}
+	return &QueryError{Op: op, Table: f.TableName, SQL: query, Err: err}
+}
 
-		if !v.Valid && !inclnull {
-			continue
-		}
+func (f *TableMap) log(query string, args []interface{}, start time.Time) {
+	if f.Logger != nil {
+		f.Logger(query, args, time.Since(start))
+	}
+}
 
-		cols = append(cols, fieldName)
+func (f *TableMap) observe(op string, start time.Time, err error) {
+	if f.Observer != nil {
+		f.Observer.ObserveQuery(op, f.TableName, time.Since(start), err)
+	}
+}
 
-		if v.Valid {
-			vals = append(vals, v.String)
-		} else {
-			vals = append(vals, "NULL")
+// SetLogger has every statement the TableMap runs passed to l, once it's
+// done. nil turns logging off.
+func (f *TableMap) SetLogger(l Logger) {
+	f.Logger = l
+}
+
+func (f *TableMap) SetDialect(d Dialect) {
+	f.Dialect = d
+}
+
+// Err returns the first error from building the query, e.g. an OrderBy on a
+// column that isn't mapped, or a field whose current value can't be stored.
+// Methods that run queries return it too.
+func (f *TableMap) Err() error {
+	if f.mapErr != nil {
+		return f.mapErr
+	}
+	if f.err != nil {
+		return f.err
+	}
+	if err := f.paramsErr(); err != nil {
+		return err
+	}
+	if err := f.dialectErr(); err != nil {
+		return err
+	}
+	for _, colname := range f.fieldOrder {
+		if check := f.Fields[colname].check; check != nil {
+			if err := check(f.Dialect); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
+}
+
+func (f *TableMap) setErr(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
 
-	// this will depend on database driver
-	var placeholders []string
-	for range cols {
-		placeholders = append(placeholders, "?")
+// quote makes name safe to use as an identifier, even if it's a reserved word
+// like "order": double quotes for SQLite and Postgres, backticks for MySQL.
+// Dotted names like schema.table are quoted a part at a time.
+func (f *TableMap) quote(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = f.quoteName(part)
 	}
+	return strings.Join(parts[:], ".")
+}
 
-	return cols, placeholders, vals
+// quoteName quotes name as a single identifier, dots and all, as for the
+// "authors.name" alias of a joined column.
+func (f *TableMap) quoteName(name string) string {
+	q := `"`
+	if f.Dialect == DialectMySQL {
+		q = "`"
+	}
+	return q + strings.Replace(name, q, q+q, -1) + q
 }
 
-type TableMapField struct {
-	Val TableMapInput
+// QuoteIdent quotes name the way the TableMap's own SQL does, for
+// hand-written fragments like RawWhere clauses and Join conditions. Quotes in
+// name are escaped, so the result is safe to interpolate.
+func (f *TableMap) QuoteIdent(name string) string {
+	return f.quote(name)
 }
 
-type TableMapInput func() sql.NullString
+func (f *TableMap) quoteAll(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = f.quote(name)
+	}
+	return quoted
+}
 
-// The ___Col methods associate the given input with a typed DB column and
-// ensure it's compatible with that column type. For example:
-// - IntCol checks to ensure the given value is a valid integer in SQL.
-// - TimeCol (TBD) would run the db function CONVERT on the value (for postgres).
+// rebind converts the ? placeholders in query to d's style: $1, $2, ... for
+// Postgres, and left alone for SQLite and MySQL. Every statement is built with
+// ? and gets rebound at the end, so this is the only place that needs to know
+// how a dialect binds parameters. A ? inside quotes isn't a placeholder and is
+// skipped.
+func rebind(d Dialect, query string) string {
+	if d != DialectPostgres {
+		return query
+	}
 
-func (f *TableMap) IntCol(name string, input TableMapInput) {
-	inputChecked := func() sql.NullString {
-		v := input()
-		_, err := strconv.Atoi(v.String)
-		if err != nil {
-			return sql.NullString{String: "", Valid: false}
+	var b strings.Builder
+	q := quoteScanner{dialect: d}
+	n := 0
+	for _, r := range query {
+		if !q.quoted(r) && r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
 		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
 
-		if v.Valid {
-			return v
-		} else {
-			return sql.NullString{String: "", Valid: false}
+// quoteScanner follows a query a rune at a time to tell which parts of it are
+// inside quotes. MySQL also lets a backslash escape a quote inside a string,
+// like 'it\'s'; the others only have doubled quotes, which take care of
+// themselves.
+type quoteScanner struct {
+	dialect Dialect
+	quote   rune
+	escaped bool
+}
+
+// quoted reports whether r is quoted, counting the quotes themselves.
+func (q *quoteScanner) quoted(r rune) bool {
+	switch {
+	case q.escaped:
+		q.escaped = false
+	case q.quote != 0:
+		if r == '\\' && q.dialect == DialectMySQL && q.quote != '`' {
+			q.escaped = true
+		} else if r == q.quote {
+			q.quote = 0
 		}
+	case r == '"' || r == '\'' || r == '`':
+		q.quote = r
+	default:
+		return false
 	}
-	f.StringCol(name, inputChecked)
+	return true
 }
 
-func (f *TableMap) StringCol(name string, input TableMapInput) {
-	m := TableMapField{Val: input}
-	f.Fields[name] = m
-	f.fieldOrder = append(f.fieldOrder, name)
+// checkArgs makes sure query has a placeholder for each of args, so a builder
+// that gets its columns, placeholders and values out of step fails with a
+// clear error rather than whatever the driver makes of it.
+func checkArgs(d Dialect, op string, query string, args []interface{}) error {
+	if n := countPlaceholders(d, query); n != len(args) {
+		return fmt.Errorf("dbtools: %s statement has %d placeholders but %d args", op, n, len(args))
+	}
+	return nil
 }
 
-// The From_____ methods basically take the column and converts it into a
-// sql.NullString.  We'll do nil-handling later in getFieldsHelper.
-
-func FromString(v *string) TableMapInput {
-	return func() sql.NullString {
-		if v == nil {
-			return sql.NullString{String: "", Valid: false}
-		} else {
-			return sql.NullString{String: *v, Valid: true}
+// countPlaceholders counts the placeholders outside quotes the way rebind
+// finds them: ? or, for Postgres, the $n it turned them into.
+func countPlaceholders(d Dialect, query string) int {
+	q := quoteScanner{dialect: d}
+	n := 0
+	prev := ' '
+	for _, r := range query {
+		switch {
+		case q.quoted(r):
+		case d == DialectPostgres && prev == '$' && r >= '0' && r <= '9':
+			n++
+		case d != DialectPostgres && r == '?':
+			n++
 		}
+		prev = r
 	}
+	return n
+}
+
+// Print writes the fields to stdout, one per line, in the order they were
+// mapped.
+func (f *TableMap) Print() {
+	f.Fprint(os.Stdout)
 }
 
-func FromInt(v *int) TableMapInput {
-	return func() sql.NullString {
-		if v == nil {
-			return sql.NullString{String: "", Valid: false}
+func (f *TableMap) Fprint(w io.Writer) {
+	for _, colname := range f.fieldOrder {
+		v := f.displayVal(f.Fields[colname])
+
+		var output string
+		if v.Valid {
+			output = v.String
 		} else {
-			s := strconv.Itoa(*v)
-			return sql.NullString{String: s, Valid: true}
+			output = "<null>"
 		}
+
+		fmt.Fprintf(w, "%s : %s\n", colname, output)
 	}
 }
 
-// setup / teardown; this should be managed by a separate db migration library
+// String is a one-line summary like `TableMap(messages){id=1, title="x",
+// body=<null>}`. Values bound as strings are quoted, so "" and <null> look
+// different.
+func (f *TableMap) String() string {
+	var fields []string
+	for _, colname := range f.fieldOrder {
+		field := f.Fields[colname]
+		v := f.displayVal(field)
+
+		arg, _ := field.arg()
+		_, isString := arg.(string)
+
+		var output string
+		switch {
+		case !v.Valid:
+			output = "<null>"
+		case isString:
+			output = strconv.Quote(v.String)
+		default:
+			output = v.String
+		}
 
-func prepareDB(db *sql.DB) {
-	dropstmt := "DROP TABLE IF EXISTS " + TABLE_NAME
-	_, err := db.Exec(dropstmt)
-	checkErr(err)
+		fields = append(fields, colname+"="+output)
+	}
+
+	return fmt.Sprintf("TableMap(%s){%s}", f.TableName, strings.Join(fields[:], ", "))
+}
 
-	createstmt := "CREATE TABLE " + TABLE_NAME + `(
-		id INTEGER PRIMARY_KEY,
-		title TEXT,
-		body TEXT
-	)`
-	_, err = db.Exec(createstmt)
-	checkErr(err)
+// Values returns each field's current value by column name, as it would be
+// bound: an int for IntCol, a time.Time for TimeCol and so on, and nil for
+// null. Handy for templates and JSON responses.
+func (f *TableMap) Values() map[string]interface{} {
+	values := make(map[string]interface{}, len(f.fieldOrder))
+	for _, colname := range f.fieldOrder {
+		v, _ := f.Fields[colname].arg()
+		values[colname] = v
+	}
+	return values
 }
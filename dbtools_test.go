@@ -0,0 +1,225 @@
+package dbtools
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testMessage is the Message from the examples.
+type testMessage struct {
+	ID    *int
+	Title *string
+	Body  *string
+}
+
+func (m *testMessage) toTableMap(db *sql.DB) *TableMap {
+	tm := NewTableMap(db, "messages")
+	tm.IntCol("id", FromInt(m.ID))
+	tm.StringCol("title", FromString(m.Title))
+	tm.StringCol("body", FromString(m.Body))
+	tm.PrimaryKeyCol("id")
+	return tm
+}
+
+// openTestDB is an in-memory SQLite database with an empty messages table. It
+// only has the one connection, since each one to :memory: gets its own
+// database.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec("CREATE TABLE messages (id INTEGER PRIMARY KEY, title TEXT, body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestFindSqlJoinsConditionsWithAnd(t *testing.T) {
+	db := openTestDB(t)
+	id, title, body := 1, "My Title", "My Body"
+	if _, err := (&testMessage{ID: &id, Title: &title, Body: &body}).toTableMap(db).Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	tm := (&testMessage{ID: &id, Title: &title}).toTableMap(db)
+	query, _ := tm.FindSql()
+	if !strings.Contains(query, " AND ") {
+		t.Errorf("FindSql with two non-null fields = %q, want the conditions joined with AND", query)
+	}
+
+	found := 0
+	err := tm.Find(func(rows *sql.Rows) error {
+		found++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != 1 {
+		t.Errorf("Find returned %d rows, want 1", found)
+	}
+}
+
+func TestCreateStoresNilAsNull(t *testing.T) {
+	db := openTestDB(t)
+	id, title := 1, "My Title"
+	if _, err := (&testMessage{ID: &id, Title: &title}).toTableMap(db).Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body sql.NullString
+	if err := db.QueryRow("SELECT body FROM messages WHERE id = 1").Scan(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Valid {
+		t.Errorf("body = %q, want NULL", body.String)
+	}
+}
+
+// orderTableMap maps a table with columns named after SQL keywords.
+func orderTableMap(db *sql.DB, id int, order string) *TableMap {
+	tm := NewTableMap(db, "select")
+	tm.IntCol("id", FromIntVal(id))
+	tm.StringCol("order", FromStringVal(order))
+	tm.PrimaryKeyCol("id")
+	return tm
+}
+
+func TestReservedWordColumns(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`CREATE TABLE "select" (id INTEGER PRIMARY KEY, "order" TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := orderTableMap(db, 1, "first").Create(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orderTableMap(db, 1, "second").Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	tm := orderTableMap(db, 1, "second").OrderBy("order", ASC)
+	query, _ := tm.FindSql()
+	if want := `SELECT "id","order" FROM "select" WHERE "id"=? AND "order"=? ORDER BY "order" ASC`; query != want {
+		t.Errorf("FindSql = %q, want %q", query, want)
+	}
+	if n, err := tm.Count(); err != nil || n != 1 {
+		t.Errorf("Count = %d, %v; want 1", n, err)
+	}
+}
+
+func TestReservedWordColumnsMySQL(t *testing.T) {
+	tm := orderTableMap(nil, 1, "first")
+	tm.SetDialect(DialectMySQL)
+
+	query, _ := tm.CreateSql()
+	if want := "INSERT INTO `select` (`id`,`order`) VALUES (?,?)"; strings.TrimSpace(query) != want {
+		t.Errorf("CreateSql = %q, want %q", query, want)
+	}
+	query, _ = tm.UpdateSql()
+	if want := "UPDATE `select` SET `order`=? WHERE `id`=?"; query != want {
+		t.Errorf("UpdateSql = %q, want %q", query, want)
+	}
+}
+
+// errObserver records the error of each statement it's told about.
+type errObserver struct {
+	errs []error
+}
+
+func (o *errObserver) ObserveQuery(op, table string, duration time.Duration, err error) {
+	o.errs = append(o.errs, err)
+}
+
+func TestObserverSeesScanErrors(t *testing.T) {
+	db := openTestDB(t)
+	obs := &errObserver{}
+	tm := NewTableMap(db, "missing")
+	tm.Observer = obs
+
+	if _, err := tm.Count(); err == nil {
+		t.Fatal("Count on a missing table = nil, want an error")
+	}
+	if len(obs.errs) != 1 || obs.errs[0] == nil {
+		t.Errorf("Observer errors = %v, want the Count's error", obs.errs)
+	}
+
+	obs.errs = nil
+	tm = (&testMessage{}).toTableMap(db)
+	tm.Observer = obs
+	var title sql.NullString
+	if err := tm.FetchScalar(&title, "title"); err != sql.ErrNoRows {
+		t.Fatalf("FetchScalar with no rows = %v, want sql.ErrNoRows", err)
+	}
+	if len(obs.errs) != 1 || obs.errs[0] != nil {
+		t.Errorf("Observer errors = %v, want one success", obs.errs)
+	}
+}
+
+func TestPingWithoutDB(t *testing.T) {
+	tm := NewTableMap(nil, "messages")
+	tm.DryRun = &DryRun{}
+	if err := tm.Ping(context.Background()); err == nil {
+		t.Error("Ping with no DB = nil, want an error")
+	}
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{`a=? AND b=?`, `a=$1 AND b=$2`},
+		{strings.Repeat("?,", 10) + "?", "$1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11"},
+		{`a=? AND b='?' AND c=?`, `a=$1 AND b='?' AND c=$2`},
+		{`"a?"=? AND b=?`, `"a?"=$1 AND b=$2`},
+		{"`a?`=?", "`a?`=$1"},
+		{`a='it''s ?' AND b=?`, `a='it''s ?' AND b=$1`},
+		{`a LIKE ? ESCAPE '\' AND b=?`, `a LIKE $1 ESCAPE '\' AND b=$2`},
+	}
+	for _, tt := range tests {
+		if got := rebind(DialectPostgres, tt.query); got != tt.want {
+			t.Errorf("rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+		if got := rebind(DialectSQLite, tt.query); got != tt.query {
+			t.Errorf("rebind(%q) on SQLite = %q, want it unchanged", tt.query, got)
+		}
+	}
+}
+
+func TestCountPlaceholders(t *testing.T) {
+	tests := []struct {
+		d     Dialect
+		query string
+		want  int
+	}{
+		{DialectSQLite, `a=? AND b=?`, 2},
+		{DialectSQLite, `a='?' AND b="?" AND c=?`, 1},
+		{DialectSQLite, "`a?`=?", 1},
+		{DialectSQLite, `a='it''s ?' AND b=?`, 1},
+		{DialectSQLite, `a LIKE ? ESCAPE '\' AND b=?`, 2},
+		{DialectMySQL, `a='it\'s' AND b=?`, 1},
+		{DialectMySQL, `a='it\'s ?' AND b=?`, 1},
+		{DialectMySQL, `a="say \"?\"" AND b=?`, 1},
+		{DialectMySQL, `a LIKE ? AND b='\\' AND c=?`, 2},
+		{DialectMySQL, "`a\\`=?", 1},
+		{DialectPostgres, `a=$1 AND b=$2`, 2},
+		{DialectPostgres, rebind(DialectPostgres, strings.Repeat("?,", 11)+"?"), 12},
+		{DialectPostgres, `a=$1 AND b='$2' AND c=?`, 1},
+		{DialectPostgres, `a LIKE $1 ESCAPE '\' AND b=$2`, 2},
+	}
+	for _, tt := range tests {
+		if got := countPlaceholders(tt.d, tt.query); got != tt.want {
+			t.Errorf("countPlaceholders(%d, %q) = %d, want %d", tt.d, tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Command examples walks through creating, updating and finding a row with
+// dbtools, against a throwaway SQLite database.
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/davecgh/go-spew/spew"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/ditsara/dbtools"
+)
+
+const TABLE_NAME = "messages"
+
+// Message and toTableMap are the only things a user of the library needs to
+// write.
+type Message struct {
+	ID    *int
+	Title *string
+	Body  *string
+}
+
+func (m *Message) toTableMap(db *sql.DB) *dbtools.TableMap {
+	tm := dbtools.NewTableMap(db, TABLE_NAME)
+	tm.IntCol("id", dbtools.FromInt(m.ID))
+	tm.StringCol("title", dbtools.FromString(m.Title))
+	tm.StringCol("body", dbtools.FromString(m.Body))
+	tm.PrimaryKeyCol("id")
+	return tm
+}
+
+func main() {
+	db, err := sql.Open("sqlite3", "./foo.db")
+	checkErr(err)
+	defer db.Close()
+
+	prepareDB(db)
+
+	id := 1
+	title := "My Title"
+	body := "My Body"
+	msg := Message{ID: &id, Title: &title, Body: &body}
+
+	tm := msg.toTableMap(db)
+	tm.Print()
+
+	_, err = tm.Create()
+	checkErr(err)
+	fmt.Println("-----------")
+
+	newTitle := "My Updated Title"
+	msg = Message{ID: &id, Title: &newTitle}
+	tm = msg.toTableMap(db)
+	_, err = tm.Update()
+	checkErr(err)
+
+	// two non-null fields, so the WHERE clause needs an AND
+	msg = Message{ID: &id, Title: &newTitle}
+	tm = msg.toTableMap(db)
+
+	// Setting the struct from the database is still pretty clunky. The
+	// alternatives are:
+	//
+	// 1. store pointers from the struct in a closure, then set
+	// value of the pointer; the problem with this is if the pointers are nil,
+	// you can't re-set the underlying value and still have it associated with
+	// the struct.
+	//
+	// 2. the method below, where I've at least abstracted away the boilerplate
+	// and the user just provides a function to process sql.Rows
+	//
+	// 3. use reflection. the performance penalty probably doesn't matter, and
+	// we can store the correct setters in a closure to prevent bugs. But it's
+	// still basically "unsafe" code.
+	//
+	// example of setting a field with reflection
+	// val := reflect.ValueOf(&n)
+	// (val.Elem()).FieldByName("title").SetString("My Title")
+	//
+	// 4. I'm sure there's also an approach using type assertions (rows.Scan into
+	// an appropriately-sized array of interface{}), and like reflection we could
+	// store the type assertion in the appropriately-typed closure. But then we
+	// still have the null pointer problem.
+	//
+	// FindInto goes with option 3. Scanning straight into the struct's own
+	// pointer fields sidesteps the null pointer problem, since database/sql
+	// allocates them for non-null values and leaves them nil otherwise. The
+	// field for each column is worked out once per struct type and cached.
+	// ScanStruct does the same for rows from hand-written queries, and Find
+	// still takes a parser (option 2) for anything else.
+
+	var fetchedMessages []Message
+	err = tm.FindInto(&fetchedMessages)
+	checkErr(err)
+	spew.Dump(fetchedMessages)
+}
+
+func checkErr(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// setup / teardown; this should be managed by a separate db migration library
+
+func prepareDB(db *sql.DB) {
+	dropstmt := "DROP TABLE IF EXISTS " + TABLE_NAME
+	_, err := db.Exec(dropstmt)
+	checkErr(err)
+
+	// the table comes from the mapping, so the two can't drift apart
+	var m Message
+	_, err = m.toTableMap(db).CreateTableIfNotExists()
+	checkErr(err)
+}
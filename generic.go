@@ -0,0 +1,38 @@
+package dbtools
+
+import (
+	"context"
+	"database/sql"
+)
+
+// FindTyped is Find with the slice handled for you: scan turns each row into
+// a T.
+func FindTyped[T any](tm *TableMap, scan func(rows *sql.Rows) (T, error)) ([]T, error) {
+	return FindTypedContext(context.Background(), tm, scan)
+}
+
+func FindTypedContext[T any](ctx context.Context, tm *TableMap, scan func(rows *sql.Rows) (T, error)) ([]T, error) {
+	var out []T
+	err := tm.FindContext(ctx, func(rows *sql.Rows) error {
+		v, err := scan(rows)
+		if err != nil {
+			return err
+		}
+
+		out = append(out, v)
+		return nil
+	})
+	return out, err
+}
+
+// FindAll is FindInto returning a []T. T must be a struct or a struct
+// pointer.
+func FindAll[T any](tm *TableMap) ([]T, error) {
+	return FindAllContext[T](context.Background(), tm)
+}
+
+func FindAllContext[T any](ctx context.Context, tm *TableMap) ([]T, error) {
+	var out []T
+	err := tm.FindIntoContext(ctx, &out)
+	return out, err
+}
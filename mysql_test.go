@@ -0,0 +1,93 @@
+//go:build mysql
+// +build mysql
+
+package dbtools
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// The MySQL tests only build with `go test -tags mysql`, and need a database
+// to use in DBTOOLS_MYSQL_DSN, like "user:pass@tcp(localhost:3306)/dbtools_test".
+// The messages table in it is dropped and recreated.
+func openMySQL(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("DBTOOLS_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("DBTOOLS_MYSQL_DSN isn't set")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS messages",
+		"CREATE TABLE messages (id INT AUTO_INCREMENT PRIMARY KEY, title TEXT, body TEXT)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func mysqlMessage(db *sql.DB, m *testMessage) *TableMap {
+	tm := m.toTableMap(db)
+	tm.SetDialect(DialectMySQL)
+	return tm
+}
+
+func TestMySQL(t *testing.T) {
+	db := openMySQL(t)
+	title, body := "My Title", "My Body"
+
+	tm := mysqlMessage(db, &testMessage{Title: &title, Body: &body})
+	tm.OmitNullPrimaryKey()
+	id, err := tm.CreateReturningId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 {
+		t.Errorf("CreateReturningId = %d, want the AUTO_INCREMENT id 1", id)
+	}
+
+	n := int(id)
+	newTitle := "Upserted"
+	if _, err := mysqlMessage(db, &testMessage{ID: &n, Title: &newTitle}).Upsert(); err != nil {
+		t.Fatal(err)
+	}
+
+	var found []testMessage
+	if err := mysqlMessage(db, &testMessage{ID: &n}).FindInto(&found); err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || *found[0].Title != newTitle || found[0].Body == nil || *found[0].Body != body {
+		t.Errorf("after Upsert found %+v, want title %q and body %q", found, newTitle, body)
+	}
+
+	second := mysqlMessage(db, &testMessage{Title: &title})
+	if _, err := second.OmitNullPrimaryKey().Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	// MySQL has no OFFSET without a LIMIT
+	found = nil
+	if err := mysqlMessage(db, &testMessage{}).OrderBy("id", ASC).Offset(1).FindInto(&found); err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || *found[0].ID != 2 {
+		t.Errorf("Offset(1) found %+v, want just row 2", found)
+	}
+
+	count, err := mysqlMessage(db, &testMessage{}).Count()
+	if err != nil || count != 2 {
+		t.Errorf("Count = %d, %v; want 2", count, err)
+	}
+}
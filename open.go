@@ -0,0 +1,48 @@
+package dbtools
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Config is the connection pool settings Open applies to the *sql.DB. See the
+// database/sql methods of the same names for what they mean. Zero fields
+// take the value from DefaultConfig.
+type Config struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// DefaultConfig is a reasonable starting point for a service: database/sql
+// itself allows unlimited connections kept forever, which can exhaust the
+// server's connection limit or hold on to connections it has dropped.
+var DefaultConfig = Config{
+	MaxOpenConns:    25,
+	MaxIdleConns:    25,
+	ConnMaxLifetime: 5 * time.Minute,
+}
+
+// Open is sql.Open with the pool set up from cfg. Like sql.Open it doesn't
+// connect yet; use a TableMap's Ping to check the database is reachable.
+func Open(driver string, dsn string, cfg Config) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxOpenConns == 0 {
+		cfg.MaxOpenConns = DefaultConfig.MaxOpenConns
+	}
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = DefaultConfig.MaxIdleConns
+	}
+	if cfg.ConnMaxLifetime == 0 {
+		cfg.ConnMaxLifetime = DefaultConfig.ConnMaxLifetime
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return db, nil
+}
@@ -0,0 +1,1075 @@
+package dbtools
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func (f *TableMap) CreateSql() (string, []interface{}) {
+	sql, vals := f.createSql()
+	sql += f.returningSql() + "\n"
+	return rebind(f.Dialect, sql), vals
+}
+
+// createSql is the bare INSERT, for the variants that add to it.
+func (f *TableMap) createSql() (string, []interface{}) {
+	cols, placeholders, vals := f.insertFields()
+
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		f.quote(f.TableName),
+		strings.Join(f.quoteAll(cols)[:], ","),
+		strings.Join(placeholders[:], ","))
+	return sql, vals
+}
+
+func (f *TableMap) Create() (sql.Result, error) {
+	return f.CreateContext(context.Background())
+}
+
+func (f *TableMap) CreateContext(ctx context.Context) (sql.Result, error) {
+	sql, vals := f.CreateSql()
+	if err := f.writeErr(); err != nil {
+		return nil, err
+	}
+	r, err := f.exec(ctx, "create", sql, vals)
+	return r, err
+}
+
+// CreateReturningId inserts the row and returns its generated id. On SQLite
+// and MySQL that's LastInsertId (the rowid, or LAST_INSERT_ID() for an
+// AUTO_INCREMENT column). Postgres drivers don't support LastInsertId, so
+// there it uses RETURNING on the primary key instead. In a dry run the id is
+// 0.
+func (f *TableMap) CreateReturningId() (int64, error) {
+	return f.CreateReturningIdContext(context.Background())
+}
+
+func (f *TableMap) CreateReturningIdContext(ctx context.Context) (int64, error) {
+	if f.Dialect != DialectPostgres {
+		r, err := f.CreateContext(ctx)
+		if err != nil || f.DryRun != nil {
+			return 0, err
+		}
+		return r.LastInsertId()
+	}
+
+	if !f.hasPrimaryKey() {
+		return 0, ErrNoPrimaryKey
+	}
+	if len(f.PrimaryKey) > 1 {
+		return 0, errors.New("dbtools: CreateReturningId needs a single-column primary key")
+	}
+
+	sql, vals := f.createSql()
+	sql = rebind(f.Dialect, sql+" RETURNING "+f.quote(f.PrimaryKey[0]))
+	if err := f.writeErr(); err != nil {
+		return 0, err
+	}
+
+	row, err := f.queryRow(ctx, "create", sql, vals)
+	if err == ErrDryRun {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = row.Scan(&id)
+	return id, err
+}
+
+// BulkCreateSql inserts all of rows in one statement. They must map the same
+// table and columns; the first row's DB and dialect are used.
+func BulkCreateSql(rows []*TableMap) (string, []interface{}, error) {
+	if len(rows) == 0 {
+		return "", nil, errors.New("dbtools: BulkCreate needs at least one row")
+	}
+
+	first := rows[0]
+	firstCols, _, _ := first.insertFields()
+
+	var tuples []string
+	var vals []interface{}
+	for i, row := range rows {
+		if row.TableName != first.TableName || !sameCols(row.fieldOrder, first.fieldOrder) {
+			return "", nil, fmt.Errorf("dbtools: BulkCreate row %d doesn't match the columns of row 0", i)
+		}
+
+		cols, placeholders, rowVals := row.insertFields()
+		if err := row.writeErr(); err != nil {
+			return "", nil, err
+		}
+		if !sameCols(cols, firstCols) {
+			return "", nil, fmt.Errorf("dbtools: BulkCreate row %d has a null primary key and row 0 doesn't, or the other way round", i)
+		}
+
+		vals = append(vals, rowVals...)
+		tuples = append(tuples, "("+strings.Join(placeholders[:], ",")+")")
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		first.quote(first.TableName),
+		strings.Join(first.quoteAll(firstCols)[:], ","),
+		strings.Join(tuples[:], ","))
+	return rebind(first.Dialect, sql), vals, nil
+}
+
+func BulkCreate(rows []*TableMap) (sql.Result, error) {
+	return BulkCreateContext(context.Background(), rows)
+}
+
+func BulkCreateContext(ctx context.Context, rows []*TableMap) (sql.Result, error) {
+	sql, vals, err := BulkCreateSql(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := rows[0].exec(ctx, "create", sql, vals)
+	return r, err
+}
+
+func sameCols(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *TableMap) FindSql() (string, []interface{}) {
+	where, vals := f.findWhereSql()
+
+	var cols []string
+	for _, col := range f.selectCols() {
+		cols = append(cols, f.quoteCol(col))
+	}
+	for _, c := range f.extraCols {
+		cols = append(cols, c.sql(f))
+	}
+
+	sel := "SELECT "
+	if f.distinct {
+		sel = "SELECT DISTINCT "
+	}
+
+	sql := fmt.Sprintf("%s%s FROM %s%s",
+		sel,
+		strings.Join(cols[:], ","),
+		f.fromSql(),
+		where)
+
+	group, vals := f.groupSql(vals)
+	sql += group
+
+	if len(f.orderBy) > 0 {
+		var order []string
+		for _, o := range f.orderBy {
+			order = append(order, o.sql(f))
+		}
+		sql += " ORDER BY " + strings.Join(order[:], ",")
+	}
+
+	// SQLite and MySQL can't OFFSET without a LIMIT. SQLite takes -1 to mean
+	// no limit; MySQL's docs suggest the largest unsigned BIGINT.
+	switch {
+	case f.limit > 0:
+		vals = append(vals, f.limit)
+		sql += " LIMIT ?"
+	case f.offset > 0 && f.Dialect == DialectSQLite:
+		sql += " LIMIT -1"
+	case f.offset > 0 && f.Dialect == DialectMySQL:
+		sql += " LIMIT 18446744073709551615"
+	}
+
+	if f.offset > 0 {
+		vals = append(vals, f.offset)
+		sql += " OFFSET ?"
+	}
+
+	if f.forUpdate {
+		sql += " FOR UPDATE"
+		if f.skipLocked {
+			sql += " SKIP LOCKED"
+		}
+	}
+
+	return rebind(f.Dialect, sql), vals
+}
+
+func (f *TableMap) Find(parser func(rows *sql.Rows) error) error {
+	return f.FindContext(context.Background(), parser)
+}
+
+func (f *TableMap) FindContext(ctx context.Context, parser func(rows *sql.Rows) error) error {
+	rows, sql, err := f.findRows(ctx)
+	if err == ErrDryRun {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err := parser(rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	return f.wrapErr("find", sql, rows.Err())
+}
+
+// FindRows runs FindSql and returns the rows as they are, for streaming them
+// or handing them to another library. The caller has to Close them, and check
+// rows.Err once done.
+func (f *TableMap) FindRows() (*sql.Rows, error) {
+	return f.FindRowsContext(context.Background())
+}
+
+func (f *TableMap) FindRowsContext(ctx context.Context) (*sql.Rows, error) {
+	rows, _, err := f.findRows(ctx)
+	return rows, err
+}
+
+// findRows also returns the SQL it ran, for wrapping errors from the rows.
+func (f *TableMap) findRows(ctx context.Context) (*sql.Rows, string, error) {
+	if err := f.Err(); err != nil {
+		return nil, "", err
+	}
+
+	sql, vals := f.FindSql()
+	rows, err := f.query(ctx, "find", sql, vals)
+	return rows, sql, err
+}
+
+// FindMaps returns each row FindSql matches as a map from column name to
+// value, for when there's no struct to scan into. Values are whatever the
+// driver returns for the column, and nil for NULL.
+func (f *TableMap) FindMaps() ([]map[string]interface{}, error) {
+	return f.FindMapsContext(context.Background())
+}
+
+func (f *TableMap) FindMapsContext(ctx context.Context) ([]map[string]interface{}, error) {
+	cols := f.findCols()
+	vals := make([]interface{}, len(cols))
+	targets := make([]interface{}, len(cols))
+	for i := range vals {
+		targets[i] = &vals[i]
+	}
+
+	var out []map[string]interface{}
+	err := f.FindContext(ctx, func(rows *sql.Rows) error {
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+
+		row := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			row[col] = vals[i]
+		}
+		out = append(out, row)
+		return nil
+	})
+	return out, err
+}
+
+// Explain returns the database's plan for FindSql, to check it uses the
+// indexes it should. It's `EXPLAIN QUERY PLAN` on SQLite and `EXPLAIN`
+// elsewhere, and the query isn't run. Each row of the plan is a line, with its
+// columns separated by tabs; NULLs are left empty.
+func (f *TableMap) Explain() (string, error) {
+	return f.ExplainContext(context.Background())
+}
+
+func (f *TableMap) ExplainContext(ctx context.Context) (string, error) {
+	if err := f.Err(); err != nil {
+		return "", err
+	}
+
+	query, vals := f.FindSql()
+	if f.Dialect == DialectSQLite {
+		query = "EXPLAIN QUERY PLAN " + query
+	} else {
+		query = "EXPLAIN " + query
+	}
+
+	rows, err := f.query(ctx, "explain", query, vals)
+	if err == ErrDryRun {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	cells := make([]sql.NullString, len(cols))
+	targets := make([]interface{}, len(cols))
+	for i := range cells {
+		targets[i] = &cells[i]
+	}
+
+	var lines []string
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return "", f.wrapErr("explain", query, err)
+		}
+		line := make([]string, len(cells))
+		for i, c := range cells {
+			line[i] = c.String
+		}
+		lines = append(lines, strings.Join(line, "\t"))
+	}
+	return strings.Join(lines, "\n"), f.wrapErr("explain", query, rows.Err())
+}
+
+func (f *TableMap) CountSql() (string, []interface{}) {
+	where, vals := f.findWhereSql()
+	sql := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", f.fromSql(), where)
+	return rebind(f.Dialect, sql), vals
+}
+
+func (f *TableMap) Count() (int64, error) {
+	return f.CountContext(context.Background())
+}
+
+func (f *TableMap) CountContext(ctx context.Context) (int64, error) {
+	if err := f.Err(); err != nil {
+		return 0, err
+	}
+
+	sql, vals := f.CountSql()
+
+	row, err := f.queryRow(ctx, "count", sql, vals)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = row.Scan(&n)
+	return n, err
+}
+
+func (f *TableMap) ExistsSql() (string, []interface{}) {
+	where, vals := f.findWhereSql()
+	sql := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s%s)", f.fromSql(), where)
+	return rebind(f.Dialect, sql), vals
+}
+
+// Exists is cheaper than Count when you only need to know whether anything
+// matches, since the database can stop at the first row.
+func (f *TableMap) Exists() (bool, error) {
+	return f.ExistsContext(context.Background())
+}
+
+func (f *TableMap) ExistsContext(ctx context.Context) (bool, error) {
+	if err := f.Err(); err != nil {
+		return false, err
+	}
+
+	sql, vals := f.ExistsSql()
+
+	row, err := f.queryRow(ctx, "exists", sql, vals)
+	if err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err = row.Scan(&exists)
+	return exists, err
+}
+
+// FetchScalarSql selects expr, like `MAX(created_at)`, from the rows FindSql
+// would match. expr goes into the SQL as-is, so like Raw it must never
+// contain user input.
+func (f *TableMap) FetchScalarSql(expr string) (string, []interface{}) {
+	where, vals := f.findWhereSql()
+	sql := fmt.Sprintf("SELECT %s FROM %s%s", expr, f.fromSql(), where)
+	return rebind(f.Dialect, sql), vals
+}
+
+// FetchScalar scans the single value of FetchScalarSql into dest, for sums,
+// maxes and one-column lookups. Aggregates of no rows give NULL, so dest
+// should be able to take one (a sql.NullInt64 say); a plain expression with
+// no matching row returns sql.ErrNoRows.
+func (f *TableMap) FetchScalar(dest interface{}, expr string) error {
+	return f.FetchScalarContext(context.Background(), dest, expr)
+}
+
+func (f *TableMap) FetchScalarContext(ctx context.Context, dest interface{}, expr string) error {
+	if err := f.Err(); err != nil {
+		return err
+	}
+
+	sql, vals := f.FetchScalarSql(expr)
+
+	row, err := f.queryRow(ctx, "fetch scalar", sql, vals)
+	if err != nil {
+		return err
+	}
+	return row.Scan(dest)
+}
+
+// UpsertSql inserts every field, updating the non-null ones (and any SetNull
+// ones) instead if a row with the same primary key already exists. To upsert
+// on a unique constraint instead, like (author_id, slug), give its columns as
+// onColumns. MySQL ignores them and updates on whichever unique key clashes.
+// With neither a primary key nor onColumns there's nothing to conflict on:
+// the SQL is a plain INSERT and Err returns ErrNoPrimaryKey.
+func (f *TableMap) UpsertSql(onColumns ...string) (string, []interface{}) {
+	sql, vals := f.createSql()
+
+	target := f.PrimaryKey
+	if len(onColumns) > 0 {
+		target = onColumns
+	}
+	if len(target) == 0 {
+		f.setErr(ErrNoPrimaryKey)
+		return rebind(f.Dialect, sql+f.returningSql()), vals
+	}
+
+	var set []string
+	for _, col := range f.fieldOrder {
+		if _, valid := f.writeArg(col); f.isPrimaryKey(col) || !valid || hasOpt(target, col) {
+			continue
+		}
+
+		if f.Dialect == DialectMySQL {
+			set = append(set, f.quote(col)+"=VALUES("+f.quote(col)+")")
+		} else {
+			set = append(set, f.quote(col)+"=excluded."+f.quote(col))
+		}
+	}
+
+	on := strings.Join(f.quoteAll(target), ",")
+	switch {
+	case f.Dialect == DialectMySQL && len(set) == 0:
+		first := f.quote(target[0])
+		sql += fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s=%s", first, first)
+	case f.Dialect == DialectMySQL:
+		sql += " ON DUPLICATE KEY UPDATE " + strings.Join(set[:], ",")
+	case len(set) == 0:
+		sql += fmt.Sprintf(" ON CONFLICT(%s) DO NOTHING", on)
+	default:
+		sql += fmt.Sprintf(" ON CONFLICT(%s) DO UPDATE SET %s",
+			on,
+			strings.Join(set[:], ","))
+	}
+
+	return rebind(f.Dialect, sql+f.returningSql()), vals
+}
+
+func (f *TableMap) Upsert(onColumns ...string) (sql.Result, error) {
+	return f.UpsertContext(context.Background(), onColumns...)
+}
+
+func (f *TableMap) UpsertContext(ctx context.Context, onColumns ...string) (sql.Result, error) {
+	if len(onColumns) == 0 && !f.hasPrimaryKey() {
+		return nil, ErrNoPrimaryKey
+	}
+	for _, col := range onColumns {
+		if !f.checkCol("Upsert", col) {
+			return nil, f.Err()
+		}
+	}
+
+	sql, vals := f.UpsertSql(onColumns...)
+	if err := f.writeErr(); err != nil {
+		return nil, err
+	}
+
+	r, err := f.exec(ctx, "upsert", sql, vals)
+	return r, err
+}
+
+// CreateIgnoreSql is CreateSql that skips the row instead of failing if it
+// clashes with an existing one on the primary key or a unique index: `ON
+// CONFLICT DO NOTHING`, or `INSERT IGNORE` on MySQL. Unlike Upsert, the
+// existing row is left as it is.
+func (f *TableMap) CreateIgnoreSql() (string, []interface{}) {
+	sql, vals := f.createSql()
+	if f.Dialect == DialectMySQL {
+		sql = "INSERT IGNORE" + strings.TrimPrefix(sql, "INSERT")
+	} else {
+		sql += " ON CONFLICT DO NOTHING"
+	}
+	return rebind(f.Dialect, sql), vals
+}
+
+// CreateIgnore returns the number of rows inserted: 0 if the row was already
+// there. Note that MySQL's INSERT IGNORE also turns some other errors, like
+// a value too long for its column, into warnings.
+func (f *TableMap) CreateIgnore() (int64, error) {
+	return f.CreateIgnoreContext(context.Background())
+}
+
+func (f *TableMap) CreateIgnoreContext(ctx context.Context) (int64, error) {
+	sql, vals := f.CreateIgnoreSql()
+	if err := f.writeErr(); err != nil {
+		return 0, err
+	}
+
+	return rowsAffected(f.exec(ctx, "create", sql, vals))
+}
+
+// FindOne hands the first matching row to parser. If nothing matches,
+// row.Scan returns sql.ErrNoRows, which parser should pass back. Errors from
+// parser are returned as-is.
+func (f *TableMap) FindOne(parser func(row *sql.Row) error) error {
+	return f.FindOneContext(context.Background(), parser)
+}
+
+func (f *TableMap) FindOneContext(ctx context.Context, parser func(row *sql.Row) error) error {
+	if err := f.Err(); err != nil {
+		return err
+	}
+
+	sql, vals := f.FindSql()
+	row, err := f.queryRow(ctx, "find", sql, vals)
+	if err != nil {
+		return err
+	}
+	return row.done(parser(row.Row))
+}
+
+// UpdateSql sets every non-null field except the primary key, so a partial
+// update leaves the other columns alone. A composite key's columns are ANDed
+// together in the WHERE, along with the version if there's a VersionCol.
+// Update returns ErrNoFields rather than run it with nothing to SET.
+func (f *TableMap) UpdateSql() (string, []interface{}) {
+	cols, vals := f.updateFields()
+
+	var set []string
+	var setVals []interface{}
+	for i, col := range cols {
+		if f.isPrimaryKey(col) || col == f.version {
+			continue
+		}
+		setVals = append(setVals, vals[i])
+		set = append(set, f.quote(col)+"=?")
+	}
+
+	where, setVals := f.pkSql(setVals)
+	if f.version != "" {
+		v, _ := f.Fields[f.version].arg()
+		set = append(set, f.quote(f.version)+"="+f.quote(f.version)+"+1")
+		where += " AND " + f.quote(f.version) + "=?"
+		setVals = append(setVals, v)
+	}
+	sql := fmt.Sprintf("UPDATE %s SET %s WHERE %s%s",
+		f.quote(f.TableName),
+		strings.Join(set[:], ","),
+		where,
+		f.returningSql())
+	return rebind(f.Dialect, sql), setVals
+}
+
+func (f *TableMap) Update() (sql.Result, error) {
+	return f.UpdateContext(context.Background())
+}
+
+func (f *TableMap) UpdateContext(ctx context.Context) (sql.Result, error) {
+	if err := f.pkErr(); err != nil {
+		return nil, err
+	}
+
+	if err := f.versionErr(); err != nil {
+		return nil, err
+	}
+	if err := f.updateSetErr(); err != nil {
+		return nil, err
+	}
+
+	sql, vals := f.UpdateSql()
+	if err := f.writeErr(); err != nil {
+		return nil, err
+	}
+
+	r, err := f.exec(ctx, "update", sql, vals)
+	if err != nil || f.version == "" || f.DryRun != nil {
+		return r, err
+	}
+
+	n, err := r.RowsAffected()
+	if err != nil {
+		return r, err
+	}
+	if n == 0 {
+		return r, ErrStaleData
+	}
+	return r, nil
+}
+
+// UpdateN is Update returning just the number of rows changed.
+func (f *TableMap) UpdateN() (int64, error) {
+	return f.UpdateNContext(context.Background())
+}
+
+func (f *TableMap) UpdateNContext(ctx context.Context) (int64, error) {
+	return rowsAffected(f.UpdateContext(ctx))
+}
+
+// BulkUpdateSql updates all of rows, by primary key, in one statement. Each
+// column is set with a CASE on the key, like
+//
+//	UPDATE "t" SET "title"=CASE WHEN "id"=? THEN ? WHEN "id"=? THEN ? ELSE "title" END
+//	WHERE ("id"=?) OR ("id"=?)
+//
+// which works on every dialect. As with Update, a row's null fields are left
+// as they are (that row falls through to the ELSE) unless SetNull. The rows
+// must map the same table and columns, and the first row's DB and dialect are
+// used. VersionCol isn't supported, since RowsAffected can't say which row was
+// stale.
+func BulkUpdateSql(rows []*TableMap) (string, []interface{}, error) {
+	if len(rows) == 0 {
+		return "", nil, errors.New("dbtools: BulkUpdate needs at least one row")
+	}
+
+	first := rows[0]
+	if first.version != "" {
+		return "", nil, errors.New("dbtools: BulkUpdate doesn't support VersionCol")
+	}
+
+	whens := map[string][]string{}
+	whenVals := map[string][]interface{}{}
+	var where []string
+	var whereVals []interface{}
+	for i, row := range rows {
+		if row.TableName != first.TableName || !sameCols(row.fieldOrder, first.fieldOrder) || !sameCols(row.PrimaryKey, first.PrimaryKey) {
+			return "", nil, fmt.Errorf("dbtools: BulkUpdate row %d doesn't match the columns of row 0", i)
+		}
+		if err := row.pkErr(); err != nil {
+			return "", nil, fmt.Errorf("dbtools: BulkUpdate row %d: %w", i, err)
+		}
+		if err := row.writeErr(); err != nil {
+			return "", nil, err
+		}
+
+		pk, pkVals := row.pkSql(nil)
+		cols, vals := row.updateFields()
+		for j, col := range cols {
+			if row.isPrimaryKey(col) {
+				continue
+			}
+			whens[col] = append(whens[col], "WHEN "+pk+" THEN ?")
+			whenVals[col] = append(whenVals[col], pkVals...)
+			whenVals[col] = append(whenVals[col], vals[j])
+		}
+		where = append(where, "("+pk+")")
+		whereVals = append(whereVals, pkVals...)
+	}
+
+	var set []string
+	var vals []interface{}
+	for _, col := range first.fieldOrder {
+		if len(whens[col]) == 0 {
+			continue
+		}
+		set = append(set, fmt.Sprintf("%s=CASE %s ELSE %s END", first.quote(col), strings.Join(whens[col], " "), first.quote(col)))
+		vals = append(vals, whenVals[col]...)
+	}
+	if len(set) == 0 {
+		return "", nil, ErrNoFields
+	}
+
+	sql := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		first.quote(first.TableName),
+		strings.Join(set[:], ","),
+		strings.Join(where[:], " OR "))
+	return rebind(first.Dialect, sql), append(vals, whereVals...), nil
+}
+
+// BulkUpdate runs BulkUpdateSql, a round trip instead of one per row.
+func BulkUpdate(rows []*TableMap) (sql.Result, error) {
+	return BulkUpdateContext(context.Background(), rows)
+}
+
+func BulkUpdateContext(ctx context.Context, rows []*TableMap) (sql.Result, error) {
+	sql, vals, err := BulkUpdateSql(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows[0].exec(ctx, "update", sql, vals)
+}
+
+// Save inserts the row if any primary key column is null, leaving it out of
+// the INSERT for the database to generate, and updates it otherwise. After an
+// insert it returns the new id (see CreateReturningId), and a TableMap from
+// NewTableMapFromStruct with a *int or *int64 key also writes it back to the
+// struct, so saving again updates the row. After an update the id is 0. A
+// key that isn't a pointer is never null, so it's always an update.
+func (f *TableMap) Save() (int64, error) {
+	return f.SaveContext(context.Background())
+}
+
+func (f *TableMap) SaveContext(ctx context.Context) (int64, error) {
+	if !f.hasPrimaryKey() {
+		return 0, ErrNoPrimaryKey
+	}
+
+	if f.pkErr() == nil {
+		_, err := f.UpdateContext(ctx)
+		return 0, err
+	}
+
+	insert := *f
+	insert.omitNullPk = true
+	id, err := insert.CreateReturningIdContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if f.setId != nil && len(f.PrimaryKey) == 1 && f.DryRun == nil {
+		f.setId(id)
+	}
+	return id, nil
+}
+
+// CreateReturning inserts the row and hands parser the columns named with
+// Returning, like server-set timestamps. Errors from parser are returned
+// as-is. MySQL has no RETURNING, so there it fails with
+// ErrReturningUnsupported.
+func (f *TableMap) CreateReturning(parser func(row *sql.Row) error) error {
+	return f.CreateReturningContext(context.Background(), parser)
+}
+
+func (f *TableMap) CreateReturningContext(ctx context.Context, parser func(row *sql.Row) error) error {
+	if err := f.returningErr(); err != nil {
+		return err
+	}
+
+	sql, vals := f.CreateSql()
+	if err := f.writeErr(); err != nil {
+		return err
+	}
+	row, err := f.queryRow(ctx, "create", sql, vals)
+	if err != nil {
+		return err
+	}
+	return row.done(parser(row.Row))
+}
+
+// UpdateReturning is CreateReturning for Update.
+func (f *TableMap) UpdateReturning(parser func(row *sql.Row) error) error {
+	return f.UpdateReturningContext(context.Background(), parser)
+}
+
+func (f *TableMap) UpdateReturningContext(ctx context.Context, parser func(row *sql.Row) error) error {
+	if err := f.returningErr(); err != nil {
+		return err
+	}
+	if err := f.pkErr(); err != nil {
+		return err
+	}
+	if err := f.versionErr(); err != nil {
+		return err
+	}
+	if err := f.updateSetErr(); err != nil {
+		return err
+	}
+
+	sql, vals := f.UpdateSql()
+	if err := f.writeErr(); err != nil {
+		return err
+	}
+	row, err := f.queryRow(ctx, "update", sql, vals)
+	if err != nil {
+		return err
+	}
+	return row.done(parser(row.Row))
+}
+
+func (f *TableMap) returningErr() error {
+	if f.Dialect == DialectMySQL {
+		return ErrReturningUnsupported
+	}
+	if len(f.returning) == 0 {
+		return errors.New("dbtools: no columns to return; call Returning first")
+	}
+	return nil
+}
+
+// UpdateWhereSql sets every non-null field on all the rows matching conds.
+// Unlike UpdateSql, the primary key is set like any other field, and it's the
+// conds that decide which rows change.
+func (f *TableMap) UpdateWhereSql(conds ...Condition) (string, []interface{}) {
+	cols, vals := f.updateFields()
+
+	var set []string
+	var setVals []interface{}
+	for i, col := range cols {
+		setVals = append(setVals, vals[i])
+		set = append(set, f.quote(col)+"=?")
+	}
+
+	where, args := f.condsSql(conds, setVals)
+	sql := fmt.Sprintf("UPDATE %s SET %s%s",
+		f.quote(f.TableName),
+		strings.Join(set[:], ","),
+		where)
+	return rebind(f.Dialect, sql), args
+}
+
+// UpdateWhere updates every row matching conds. With no conds it returns
+// ErrNoConditions rather than touching the whole table; use UpdateAll for
+// that.
+func (f *TableMap) UpdateWhere(conds ...Condition) (sql.Result, error) {
+	return f.UpdateWhereContext(context.Background(), conds...)
+}
+
+func (f *TableMap) UpdateWhereContext(ctx context.Context, conds ...Condition) (sql.Result, error) {
+	if len(conds) == 0 {
+		return nil, ErrNoConditions
+	}
+	return f.updateWhere(ctx, conds)
+}
+
+// UpdateAll sets the non-null fields on every row in the table.
+func (f *TableMap) UpdateAll() (sql.Result, error) {
+	return f.UpdateAllContext(context.Background())
+}
+
+func (f *TableMap) UpdateAllContext(ctx context.Context) (sql.Result, error) {
+	return f.updateWhere(ctx, nil)
+}
+
+func (f *TableMap) updateWhere(ctx context.Context, conds []Condition) (sql.Result, error) {
+	if err := f.condsErr(conds); err != nil {
+		return nil, err
+	}
+	if cols, _ := f.updateFields(); len(cols) == 0 {
+		return nil, ErrNoFields
+	}
+
+	sql, vals := f.UpdateWhereSql(conds...)
+	if err := f.writeErr(); err != nil {
+		return nil, err
+	}
+
+	r, err := f.exec(ctx, "update", sql, vals)
+	return r, err
+}
+
+// DeleteSql deletes by primary key when one is configured. Otherwise it
+// deletes by example, matching every non-null field like FindSql does. A key
+// naming a column that isn't mapped is ErrNoPrimaryKey (see Err), with no SQL,
+// rather than a delete by example.
+func (f *TableMap) DeleteSql() (string, []interface{}) {
+	if len(f.PrimaryKey) > 0 && !f.hasPrimaryKey() {
+		f.setErr(ErrNoPrimaryKey)
+		return "", nil
+	}
+	if len(f.PrimaryKey) > 0 {
+		where, vals := f.pkSql(nil)
+		sql := fmt.Sprintf("DELETE FROM %s WHERE %s", f.quote(f.TableName), where)
+		return rebind(f.Dialect, sql), vals
+	}
+
+	cols, placeholders, vals := f.GetFieldsWithoutNulls()
+
+	var where []string
+	for i, col := range cols {
+		cond := f.quote(col) + "=" + placeholders[i]
+		where = append(where, cond)
+	}
+
+	sql := fmt.Sprintf("DELETE FROM %s WHERE %s",
+		f.quote(f.TableName),
+		strings.Join(where[:], " AND "))
+	return rebind(f.Dialect, sql), vals
+}
+
+// Delete returns the sql.Result as-is; check RowsAffected to see whether
+// anything matched.
+func (f *TableMap) Delete() (sql.Result, error) {
+	return f.DeleteContext(context.Background())
+}
+
+func (f *TableMap) DeleteContext(ctx context.Context) (sql.Result, error) {
+	if err := f.Err(); err != nil {
+		return nil, err
+	}
+	if len(f.PrimaryKey) > 0 {
+		if err := f.pkErr(); err != nil {
+			return nil, err
+		}
+	}
+
+	sql, vals := f.DeleteSql()
+	if len(vals) == 0 {
+		return nil, ErrNoConditions
+	}
+
+	r, err := f.exec(ctx, "delete", sql, vals)
+	return r, err
+}
+
+// DeleteN is Delete returning just the number of rows deleted.
+func (f *TableMap) DeleteN() (int64, error) {
+	return f.DeleteNContext(context.Background())
+}
+
+func (f *TableMap) DeleteNContext(ctx context.Context) (int64, error) {
+	return rowsAffected(f.DeleteContext(ctx))
+}
+
+func rowsAffected(r sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return r.RowsAffected()
+}
+
+// insertFields is GetFields with the defaults filled in for null fields. A
+// DefaultSql expression takes the place of its placeholder and has no value.
+// With OmitNullPrimaryKey, a null key column is left out altogether.
+func (f *TableMap) insertFields() ([]string, []string, []interface{}) {
+	var cols, placeholders []string
+	var vals []interface{}
+
+	for _, fieldName := range f.fieldOrder {
+		field := f.Fields[fieldName]
+		v, valid := f.writeArg(fieldName)
+
+		switch {
+		case !valid && f.omitNullPk && f.isPrimaryKey(fieldName) && field.def == nil && field.defSql == "":
+			continue
+		case !valid && field.defSql != "":
+			cols = append(cols, fieldName)
+			placeholders = append(placeholders, field.defSql)
+			continue
+		case !valid && field.def != nil:
+			v, _ = field.def()
+		}
+
+		cols = append(cols, fieldName)
+		placeholders = append(placeholders, "?")
+		vals = append(vals, v)
+	}
+
+	return cols, placeholders, vals
+}
+
+// updateFields is GetFieldsWithoutNulls for UPDATE's SET, which also has the
+// SetNull columns.
+func (f *TableMap) updateFields() ([]string, []interface{}) {
+	var cols []string
+	var vals []interface{}
+	for _, fieldName := range f.fieldOrder {
+		if v, valid := f.writeArg(fieldName); valid {
+			cols = append(cols, fieldName)
+			vals = append(vals, v)
+		}
+	}
+	return cols, vals
+}
+
+// updateSetErr is ErrNoFields if UpdateSql would have nothing to SET: every
+// field but the key is null. A VersionCol is always bumped, so that's enough.
+func (f *TableMap) updateSetErr() error {
+	if f.version != "" {
+		return nil
+	}
+	cols, _ := f.updateFields()
+	for _, col := range cols {
+		if !f.isPrimaryKey(col) {
+			return nil
+		}
+	}
+	return ErrNoFields
+}
+
+// writeArg is the field's value to write: nil but valid if it's been SetNull,
+// so it's written as NULL rather than skipped or defaulted.
+func (f *TableMap) writeArg(col string) (interface{}, bool) {
+	if f.setNull[col] {
+		return nil, true
+	}
+	return f.Fields[col].arg()
+}
+
+// GetFieldsWithoutNulls and GetFields return the column names, a ? placeholder
+// for each, and the values to bind, in mapping order.
+func (f *TableMap) GetFieldsWithoutNulls() ([]string, []string, []interface{}) {
+	return f.getFieldsHelper(false)
+}
+
+func (f *TableMap) GetFields() ([]string, []string, []interface{}) {
+	return f.getFieldsHelper(true)
+}
+
+func (f *TableMap) getFieldsHelper(inclnull bool) ([]string, []string, []interface{}) {
+	var cols []string
+	var vals []interface{}
+
+	for _, fieldName := range f.fieldOrder {
+		v, valid := f.Fields[fieldName].arg()
+
+		if !valid && !inclnull {
+			continue
+		}
+
+		cols = append(cols, fieldName)
+		vals = append(vals, v)
+	}
+
+	var placeholders []string
+	for range cols {
+		placeholders = append(placeholders, "?")
+	}
+
+	return cols, placeholders, vals
+}
+
+func (f *TableMap) DeleteWhereSql(conds ...Condition) (string, []interface{}) {
+	where, vals := f.condsSql(conds, nil)
+	sql := fmt.Sprintf("DELETE FROM %s%s", f.quote(f.TableName), where)
+	return rebind(f.Dialect, sql), vals
+}
+
+// DeleteWhere deletes every row matching conds. It refuses to run without any
+// conds (returning ErrNoConditions); to empty the table, use DeleteAll.
+func (f *TableMap) DeleteWhere(conds ...Condition) (sql.Result, error) {
+	return f.DeleteWhereContext(context.Background(), conds...)
+}
+
+func (f *TableMap) DeleteWhereContext(ctx context.Context, conds ...Condition) (sql.Result, error) {
+	if len(conds) == 0 {
+		return nil, ErrNoConditions
+	}
+	return f.deleteWhere(ctx, conds)
+}
+
+// DeleteAll deletes every row in the table.
+func (f *TableMap) DeleteAll() (sql.Result, error) {
+	return f.DeleteAllContext(context.Background())
+}
+
+func (f *TableMap) DeleteAllContext(ctx context.Context) (sql.Result, error) {
+	return f.deleteWhere(ctx, nil)
+}
+
+func (f *TableMap) deleteWhere(ctx context.Context, conds []Condition) (sql.Result, error) {
+	if err := f.condsErr(conds); err != nil {
+		return nil, err
+	}
+	if err := f.Err(); err != nil {
+		return nil, err
+	}
+
+	sql, vals := f.DeleteWhereSql(conds...)
+	r, err := f.exec(ctx, "delete", sql, vals)
+	return r, err
+}
@@ -0,0 +1,22 @@
+package dbtools
+
+import "testing"
+
+func TestDeleteWithUnmappedPrimaryKey(t *testing.T) {
+	db := openTestDB(t)
+	id, body := 1, "My Body"
+	if _, err := (&testMessage{ID: &id, Body: &body}).toTableMap(db).Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	tm := NewTableMap(db, "messages")
+	tm.StringCol("body", FromString(&body))
+	tm.PrimaryKeyCol("ID")
+	if _, err := tm.Delete(); err != ErrNoPrimaryKey {
+		t.Errorf("Delete with an unmapped key = %v, want ErrNoPrimaryKey", err)
+	}
+
+	if n, err := (&testMessage{}).toTableMap(db).Count(); err != nil || n != 1 {
+		t.Errorf("Count after Delete = %d, %v; want 1", n, err)
+	}
+}
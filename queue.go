@@ -0,0 +1,102 @@
+package dbtools
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ClaimNext treats the table as a job queue: in one transaction it takes the
+// first row (by orderCol) whose statusCol is pending, hands it to fn, and sets
+// statusCol to claimed. For example:
+//
+//	ok, err := jobs.ClaimNext("status", "pending", "running", "created_at",
+//		func(job *dbtools.TableMap) error {
+//			return job.FindOneInto(&j)
+//		})
+//
+// The row is selected with `FOR UPDATE SKIP LOCKED`, so workers claiming at
+// the same time each get a different row instead of waiting on each other's
+// lock. job is a TableMap bound to the transaction with a condition on the
+// row's primary key, which has to be a single column. If fn returns an error
+// the transaction is rolled back and the row stays pending. ok is false if
+// there was nothing to claim.
+//
+// Any Where conditions on f narrow the rows considered; its field values, and
+// whatever else it selects, joins or sorts by, don't. In a dry run only the
+// SELECT is recorded, and ok is false. It's for Postgres and MySQL (8.0+);
+// SQLite has no row locks, so there it's an error.
+func (f *TableMap) ClaimNext(statusCol, pending, claimed, orderCol string, fn func(job *TableMap) error) (bool, error) {
+	return f.ClaimNextContext(context.Background(), statusCol, pending, claimed, orderCol, fn)
+}
+
+func (f *TableMap) ClaimNextContext(ctx context.Context, statusCol, pending, claimed, orderCol string, fn func(job *TableMap) error) (ok bool, err error) {
+	if f.Dialect == DialectSQLite {
+		return false, errors.New("dbtools: SQLite doesn't support FOR UPDATE SKIP LOCKED, so ClaimNext can't be used")
+	}
+	if len(f.PrimaryKey) != 1 {
+		return false, errors.New("dbtools: ClaimNext needs a single-column primary key")
+	}
+	pk := f.PrimaryKey[0]
+	if err := f.Err(); err != nil {
+		return false, err
+	}
+
+	// only f's conditions carry over; anything else it selects or joins
+	// would get in the way of scanning the id
+	base := f.Clone()
+	next := f.Clone().Reset()
+	next.conditions = base.conditions
+	next.params, next.paramNames = base.params, base.paramNames
+	next.withDeleted = base.withDeleted
+	next.condsOnly = true
+	// orderCol needn't be mapped any more than statusCol, so it's checked
+	// the way Where checks columns rather than with OrderBy
+	if _, ok := f.Fields[orderCol]; !ok && !validColRef(orderCol) {
+		return false, fmt.Errorf("dbtools: ClaimNext on invalid column %q", orderCol)
+	}
+	next.Select(pk).Where(statusCol, "=", pending).Limit(1).ForUpdate()
+	next.orderBy = []orderClause{{col: orderCol, dir: ASC}}
+	next.skipLocked = true
+	if err := next.Err(); err != nil {
+		return false, err
+	}
+	query, vals := next.FindSql()
+
+	if f.DryRun != nil {
+		_, err := next.queryRow(ctx, "claim", query, vals)
+		if err == ErrDryRun {
+			err = nil
+		}
+		return false, err
+	}
+
+	err = inTx(ctx, f.DB, func(tx *sql.Tx) error {
+		row, err := next.WithTx(tx).queryRow(ctx, "claim", query, vals)
+		if err != nil {
+			return err
+		}
+		var id interface{}
+		if err := row.Scan(&id); err == sql.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		job := f.Clone().Reset().WithTx(tx)
+		job.condsOnly = true
+		job.Where(pk, "=", id)
+		if err := fn(job); err != nil {
+			return err
+		}
+
+		update := rebind(f.Dialect, fmt.Sprintf("UPDATE %s SET %s=? WHERE %s=?", f.quote(f.TableName), f.quote(statusCol), f.quote(pk)))
+		if _, err := job.exec(ctx, "claim", update, []interface{}{claimed, id}); err != nil {
+			return err
+		}
+		ok = true
+		return nil
+	})
+	return ok, err
+}
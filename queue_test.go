@@ -0,0 +1,23 @@
+package dbtools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClaimNextOrdersByUnmappedColumn(t *testing.T) {
+	tm := (&testMessage{}).toTableMap(nil)
+	tm.SetDialect(DialectPostgres)
+	tm.DryRun = &DryRun{}
+
+	if _, err := tm.ClaimNext("status", "pending", "running", "created_at", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(tm.DryRun.Statements) != 1 || !strings.Contains(tm.DryRun.Statements[0].SQL, `ORDER BY "created_at" ASC`) {
+		t.Errorf("ClaimNext ran %v, want a SELECT ordered by created_at", tm.DryRun.Statements)
+	}
+
+	if _, err := tm.ClaimNext("status", "pending", "running", "created_at; --", nil); err == nil {
+		t.Error("ClaimNext with an invalid orderCol = nil, want an error")
+	}
+}
@@ -0,0 +1,127 @@
+package dbtools
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Repository groups the usual queries for one table behind a mapping
+// function, so callers deal in Ts rather than TableMaps. T must be a struct,
+// and the mapping has to mark the primary key for FindByID, FindByIDs, Update
+// and Delete. For example:
+//
+//	repo := dbtools.NewRepository(db, "messages", func(tm *dbtools.TableMap, m *Message) {
+//		tm.IntCol("id", dbtools.FromInt(m.ID))
+//		tm.StringCol("title", dbtools.FromString(m.Title))
+//		tm.PrimaryKeyCol("id")
+//	})
+type Repository[T any] struct {
+	DB        *sql.DB
+	TableName string
+	mapping   func(tm *TableMap, v *T)
+}
+
+func NewRepository[T any](db *sql.DB, tableName string, mapping func(tm *TableMap, v *T)) *Repository[T] {
+	return &Repository[T]{DB: db, TableName: tableName, mapping: mapping}
+}
+
+// Map returns a TableMap for v, for anything the Repository doesn't cover.
+func (r *Repository[T]) Map(v *T) *TableMap {
+	tm := NewTableMap(r.DB, r.TableName)
+	r.mapping(tm, v)
+	return tm
+}
+
+// query is a TableMap for reads: the fields of a zero T would otherwise
+// filter on their zero values.
+func (r *Repository[T]) query() *TableMap {
+	var zero T
+	tm := r.Map(&zero)
+	tm.condsOnly = true
+	return tm
+}
+
+func (r *Repository[T]) Insert(v T) (sql.Result, error) {
+	return r.InsertContext(context.Background(), v)
+}
+
+func (r *Repository[T]) InsertContext(ctx context.Context, v T) (sql.Result, error) {
+	return r.Map(&v).CreateContext(ctx)
+}
+
+// FindByID returns sql.ErrNoRows if there's no row with that primary key.
+// It only works for single-column keys.
+func (r *Repository[T]) FindByID(id interface{}) (T, error) {
+	return r.FindByIDContext(context.Background(), id)
+}
+
+func (r *Repository[T]) FindByIDContext(ctx context.Context, id interface{}) (T, error) {
+	var out T
+	tm := r.query()
+	if len(tm.PrimaryKey) != 1 {
+		return out, errors.New("dbtools: FindByID needs a single-column primary key")
+	}
+
+	err := tm.Where(tm.PrimaryKey[0], "=", id).FindOneIntoContext(ctx, &out)
+	return out, err
+}
+
+// FindByIDs looks up all of ids in one `IN (...)` query. The map is keyed by
+// each row's primary key as the mapping binds it, e.g. an int for IntCol, so
+// pass ids of that type to look them up again. Missing ids are left out.
+func (r *Repository[T]) FindByIDs(ids []interface{}) (map[interface{}]*T, error) {
+	return r.FindByIDsContext(context.Background(), ids)
+}
+
+func (r *Repository[T]) FindByIDsContext(ctx context.Context, ids []interface{}) (map[interface{}]*T, error) {
+	tm := r.query()
+	if len(tm.PrimaryKey) != 1 {
+		return nil, errors.New("dbtools: FindByIDs needs a single-column primary key")
+	}
+
+	pk := tm.PrimaryKey[0]
+	found, err := FindAllContext[*T](ctx, tm.WhereIn(pk, ids))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[interface{}]*T, len(found))
+	for _, v := range found {
+		id, _ := r.Map(v).Fields[pk].arg()
+		out[id] = v
+	}
+	return out, nil
+}
+
+// FindWhere returns every row matching all of conds, or the whole table if
+// there aren't any.
+func (r *Repository[T]) FindWhere(conds ...Condition) ([]T, error) {
+	return r.FindWhereContext(context.Background(), conds...)
+}
+
+func (r *Repository[T]) FindWhereContext(ctx context.Context, conds ...Condition) ([]T, error) {
+	tm := r.query()
+	for _, c := range conds {
+		tm.WhereCond(c)
+	}
+	return FindAllContext[T](ctx, tm)
+}
+
+func (r *Repository[T]) Update(v T) (sql.Result, error) {
+	return r.UpdateContext(context.Background(), v)
+}
+
+func (r *Repository[T]) UpdateContext(ctx context.Context, v T) (sql.Result, error) {
+	return r.Map(&v).UpdateContext(ctx)
+}
+
+func (r *Repository[T]) Delete(v T) (sql.Result, error) {
+	return r.DeleteContext(context.Background(), v)
+}
+
+// DeleteContext deletes by primary key; with no key every non-null field
+// has to match, like TableMap.Delete.
+func (r *Repository[T]) DeleteContext(ctx context.Context, v T) (sql.Result, error) {
+	return r.Map(&v).DeleteContext(ctx)
+}
@@ -0,0 +1,49 @@
+package dbtools
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy says which errors Retry retries and how often. Only errors
+// Retryable returns true for are retried, so a nil Retryable never retries;
+// it's up to the caller to decide what's safe to run twice.
+type RetryPolicy struct {
+	// Attempts is the most times fn runs, counting the first. Less than 1
+	// means 1.
+	Attempts int
+
+	// Backoff is the wait before the first retry. It doubles after each one.
+	Backoff time.Duration
+
+	Retryable func(err error) bool
+}
+
+// Retry runs fn until it succeeds, fails with an error p doesn't retry, or
+// runs out of attempts, and returns the last error. It's opt-in per call,
+// for transient failures like Postgres serialization errors:
+//
+//	err := dbtools.Retry(ctx, policy, func() error {
+//		_, err := tm.UpdateContext(ctx)
+//		return err
+//	})
+//
+// It stops waiting early if ctx is done, returning ctx's error.
+func Retry(ctx context.Context, p RetryPolicy, fn func() error) error {
+	wait := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || p.Retryable == nil || !p.Retryable(err) {
+			return err
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		wait *= 2
+	}
+}
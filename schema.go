@@ -0,0 +1,173 @@
+package dbtools
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// colKind is what a column was mapped as: which ___Col method, roughly.
+type colKind int
+
+const (
+	kindUnknown colKind = iota
+	kindInt
+	kindInt64
+	kindFloat
+	kindBool
+	kindTime
+	kindString
+	kindJSON
+	kindBlob
+	kindDecimal
+	kindIntArray
+	kindStringArray
+)
+
+// sqlType is the column type CreateTableSql declares for field. It's empty
+// for ValueCol columns, since there's no telling what they hold.
+func (f *TableMap) sqlType(field TableMapField, pk bool) string {
+	switch f.Dialect {
+	case DialectPostgres:
+		switch field.kind {
+		case kindInt:
+			return "INTEGER"
+		case kindInt64:
+			return "BIGINT"
+		case kindFloat:
+			return "DOUBLE PRECISION"
+		case kindBool:
+			return "BOOLEAN"
+		case kindTime:
+			return "TIMESTAMPTZ"
+		case kindString, kindJSON:
+			return "TEXT"
+		case kindBlob:
+			return "BYTEA"
+		case kindDecimal:
+			return "NUMERIC"
+		case kindIntArray:
+			return "INTEGER[]"
+		case kindStringArray:
+			return "TEXT[]"
+		}
+	case DialectMySQL:
+		switch field.kind {
+		case kindInt:
+			return "INTEGER"
+		case kindInt64:
+			return "BIGINT"
+		case kindFloat:
+			return "DOUBLE"
+		case kindBool:
+			return "BOOLEAN"
+		case kindTime:
+			return "DATETIME(6)"
+		case kindString, kindJSON:
+			// MySQL can't index a TEXT column without a prefix length
+			if pk {
+				return "VARCHAR(255)"
+			}
+			return "TEXT"
+		case kindBlob:
+			return "LONGBLOB"
+		case kindDecimal:
+			return fmt.Sprintf("DECIMAL(65,%d)", field.scale)
+		}
+	default:
+		switch field.kind {
+		// an INTEGER primary key is SQLite's rowid, so it's assigned on insert
+		case kindInt, kindInt64:
+			return "INTEGER"
+		case kindFloat:
+			return "REAL"
+		case kindBool:
+			return "BOOLEAN"
+		case kindTime:
+			return "TIMESTAMP"
+		case kindString, kindJSON:
+			return "TEXT"
+		case kindBlob:
+			return "BLOB"
+		case kindDecimal:
+			return "NUMERIC"
+		}
+	}
+	return ""
+}
+
+// CreateTableSql is a CREATE TABLE with a column for each field, in mapping
+// order, typed for the dialect from the ___Col method that mapped it, plus
+// the primary key if there is one. It's meant for tests and throwaway
+// databases; real schemas belong in migrations. Columns mapped with ValueCol
+// have no type to go on, which is an error except on SQLite, where columns
+// don't need one.
+func (f *TableMap) CreateTableSql() (string, error) {
+	return f.createTableSql(false)
+}
+
+func (f *TableMap) createTableSql(ifNotExists bool) (string, error) {
+	if len(f.fieldOrder) == 0 {
+		return "", ErrNoFields
+	}
+
+	var defs []string
+	for _, col := range f.fieldOrder {
+		typ := f.sqlType(f.Fields[col], f.isPrimaryKey(col))
+		if typ == "" && f.Dialect != DialectSQLite {
+			return "", fmt.Errorf("dbtools: no SQL type for column %s; create the table by hand", col)
+		}
+		defs = append(defs, strings.TrimSpace(f.quote(col)+" "+typ))
+	}
+	if len(f.PrimaryKey) > 0 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(f.quoteAll(f.PrimaryKey), ",")+")")
+	}
+
+	create := "CREATE TABLE "
+	if ifNotExists {
+		create += "IF NOT EXISTS "
+	}
+	return create + f.quote(f.TableName) + " (" + strings.Join(defs[:], ", ") + ")", nil
+}
+
+// CreateTableIfNotExists runs CreateTableSql, leaving the table alone if it's
+// already there.
+func (f *TableMap) CreateTableIfNotExists() (sql.Result, error) {
+	return f.CreateTableIfNotExistsContext(context.Background())
+}
+
+func (f *TableMap) CreateTableIfNotExistsContext(ctx context.Context) (sql.Result, error) {
+	if f.mapErr != nil {
+		return nil, f.mapErr
+	}
+
+	sql, err := f.createTableSql(true)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := f.exec(ctx, "create table", sql, nil)
+	return r, err
+}
+
+// TruncateSql empties the table with TRUNCATE TABLE, or DELETE FROM on
+// SQLite, which has no TRUNCATE. Unlike DeleteAll it ignores any conditions,
+// and on Postgres and MySQL it's much faster, but it can't be undone by
+// rolling back on MySQL.
+func (f *TableMap) TruncateSql() string {
+	if f.Dialect == DialectSQLite {
+		return "DELETE FROM " + f.quote(f.TableName)
+	}
+	return "TRUNCATE TABLE " + f.quote(f.TableName)
+}
+
+// Truncate is meant for test setup and teardown.
+func (f *TableMap) Truncate() (sql.Result, error) {
+	return f.TruncateContext(context.Background())
+}
+
+func (f *TableMap) TruncateContext(ctx context.Context) (sql.Result, error) {
+	r, err := f.exec(ctx, "truncate", f.TruncateSql(), nil)
+	return r, err
+}
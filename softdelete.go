@@ -0,0 +1,69 @@
+package dbtools
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SoftDeleteCol turns on soft deletes, with col (usually deleted_at) holding
+// when a row was deleted. SoftDelete sets it instead of removing the row, and
+// Find, Count and Exists leave out rows where it's set, unless WithDeleted is
+// used. col doesn't have to be mapped. Delete still removes rows for good.
+func (f *TableMap) SoftDeleteCol(col string) *TableMap {
+	f.softDelete = col
+	return f
+}
+
+// WithDeleted has the next reads include soft-deleted rows.
+func (f *TableMap) WithDeleted() *TableMap {
+	f.withDeleted = true
+	return f
+}
+
+// SoftDeleteSql sets the soft-delete column of the row with this primary key
+// to the current time, in UTC like FromTime.
+func (f *TableMap) SoftDeleteSql() (string, []interface{}) {
+	where, vals := f.pkSql([]interface{}{time.Now().UTC()})
+	sql := fmt.Sprintf("UPDATE %s SET %s=? WHERE %s",
+		f.quote(f.TableName),
+		f.quote(f.softDelete),
+		where)
+	return rebind(f.Dialect, sql), vals
+}
+
+func (f *TableMap) SoftDelete() (sql.Result, error) {
+	return f.SoftDeleteContext(context.Background())
+}
+
+func (f *TableMap) SoftDeleteContext(ctx context.Context) (sql.Result, error) {
+	if f.softDelete == "" {
+		return nil, errors.New("dbtools: SoftDelete needs SoftDeleteCol")
+	}
+	if err := f.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.pkErr(); err != nil {
+		return nil, err
+	}
+
+	sql, vals := f.SoftDeleteSql()
+	r, err := f.exec(ctx, "delete", sql, vals)
+	return r, err
+}
+
+// findWhereSql is whereSql for reads, which skip soft-deleted rows.
+func (f *TableMap) findWhereSql() (string, []interface{}) {
+	where, vals := f.whereSql()
+	if f.softDelete == "" || f.withDeleted {
+		return where, vals
+	}
+
+	cond := f.quoteCol(f.softDelete) + " IS NULL"
+	if where == "" {
+		return " WHERE " + cond, vals
+	}
+	return where + " AND " + cond, vals
+}
@@ -0,0 +1,406 @@
+package dbtools
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewTableMapFromStruct maps every exported field of the struct v points to,
+// picking the ___Col method from the field's Go type. The column name comes
+// from a `db:"name"` tag, falling back to the lowercased field name; `db:"-"`
+// skips the field, `db:"name,pk"` marks a primary key column and
+// `db:"name,json"` maps it with JSONCol. []int and []string fields are
+// Postgres arrays (see ArrayCol).
+//
+// Pointer fields are nullable. The TableMap reads through to the struct, so
+// it sees any changes made to v afterwards.
+func NewTableMapFromStruct(db *sql.DB, tableName string, v interface{}) (*TableMap, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dbtools: NewTableMapFromStruct needs a pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+
+	tm := NewTableMap(db, tableName)
+	for i := 0; i < rv.NumField(); i++ {
+		sf := rv.Type().Field(i)
+		col, opts, ok := structColumn(sf)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		if hasOpt(opts, "pk") {
+			tm.PrimaryKey = append(tm.PrimaryKey, col)
+			if pkField(tm, col, fv) {
+				continue
+			}
+		}
+
+		if hasOpt(opts, "json") {
+			tm.JSONCol(col, fv.Addr().Interface())
+			continue
+		}
+
+		// everything goes through the field's address, read when the
+		// statement is built, so pointer fields set later are seen too
+		switch p := fv.Addr().Interface().(type) {
+		case *int:
+			tm.IntCol(col, FromInt(p))
+		case **int:
+			tm.IntCol(col, func() sql.NullString { return FromInt(*p)() })
+		case *int64:
+			tm.Int64Col(col, FromInt64(p))
+		case **int64:
+			tm.Int64Col(col, func() sql.NullString { return FromInt64(*p)() })
+		case *float64:
+			tm.FloatCol(col, FromFloat(p))
+		case **float64:
+			tm.FloatCol(col, func() sql.NullString { return FromFloat(*p)() })
+		case *bool:
+			tm.BoolCol(col, FromBool(p))
+		case **bool:
+			tm.BoolCol(col, func() sql.NullString { return FromBool(*p)() })
+		case *time.Time:
+			tm.TimeCol(col, FromTime(p))
+		case **time.Time:
+			tm.TimeCol(col, func() sql.NullString { return FromTime(*p)() })
+		case *string:
+			tm.StringCol(col, FromString(p))
+		case **string:
+			tm.StringCol(col, func() sql.NullString { return FromString(*p)() })
+		case *[]int:
+			tm.IntArrayCol(col, FromIntSlice(p))
+		case **[]int:
+			tm.IntArrayCol(col, func() sql.NullString { return FromIntSlice(*p)() })
+		case *[]string:
+			tm.StringArrayCol(col, FromStringSlice(p))
+		case **[]string:
+			tm.StringArrayCol(col, func() sql.NullString { return FromStringSlice(*p)() })
+		case *[]byte:
+			tm.BlobCol(col, func() (interface{}, bool) { return FromBytes(*p)() })
+		case **[]byte:
+			tm.BlobCol(col, func() (interface{}, bool) {
+				if *p == nil {
+					return nil, false
+				}
+				return FromBytes(**p)()
+			})
+		default:
+			return nil, fmt.Errorf("dbtools: unsupported type %s for field %s", sf.Type, sf.Name)
+		}
+	}
+
+	return tm, nil
+}
+
+// pkField maps a *int or *int64 primary key field so that Save can write a
+// generated id back to it. The field is read each time rather than once, so
+// the TableMap sees the pointer Save sets. It's false for other types, which
+// are mapped as usual.
+func pkField(tm *TableMap, col string, fv reflect.Value) bool {
+	switch p := fv.Addr().Interface().(type) {
+	case **int:
+		tm.IntCol(col, func() sql.NullString { return FromInt(*p)() })
+		tm.setId = func(id int64) { v := int(id); *p = &v }
+	case **int64:
+		tm.Int64Col(col, func() sql.NullString { return FromInt64(*p)() })
+		tm.setId = func(id int64) { *p = &id }
+	default:
+		return false
+	}
+	return true
+}
+
+// structColumn reads the column name and options (like "pk") for a struct
+// field from its db tag. ok is false for unexported fields and fields tagged
+// `db:"-"`.
+func structColumn(sf reflect.StructField) (col string, opts []string, ok bool) {
+	if sf.PkgPath != "" {
+		return "", nil, false
+	}
+
+	tag := strings.Split(sf.Tag.Get("db"), ",")
+	col = tag[0]
+	if col == "-" {
+		return "", nil, false
+	}
+	if col == "" {
+		col = strings.ToLower(sf.Name)
+	}
+	return col, tag[1:], true
+}
+
+func hasOpt(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// FindOneInto is FindInto for a single struct; dest must be a pointer to one.
+// It returns sql.ErrNoRows if nothing matches.
+func (f *TableMap) FindOneInto(dest interface{}) error {
+	return f.FindOneIntoContext(context.Background(), dest)
+}
+
+func (f *TableMap) FindOneIntoContext(ctx context.Context, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dbtools: FindOneInto needs a pointer to a struct, got %T", dest)
+	}
+
+	rows, query, err := f.findRows(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return f.wrapErr("find", query, err)
+		}
+		return sql.ErrNoRows
+	}
+
+	var s structScanner
+	return s.scan(f, rows, v.Elem())
+}
+
+// FindInto appends every row FindSql matches to dest, which must be a pointer
+// to a slice of structs or struct pointers. The columns the database says it
+// returned are matched to struct fields by db tag or, for untagged fields, by
+// name ignoring case. Set the TableMap's SnakeCase to have untagged fields
+// also match snake_case columns, so author_name fills AuthorName. Every column
+// needs a field, so a column added to the TableMap but not the struct is an
+// error rather than being dropped; fields without a column are left alone.
+func (f *TableMap) FindInto(dest interface{}) error {
+	return f.FindIntoContext(context.Background(), dest)
+}
+
+func (f *TableMap) FindIntoContext(ctx context.Context, dest interface{}) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("dbtools: FindInto needs a pointer to a slice, got %T", dest)
+	}
+	slice = slice.Elem()
+
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("dbtools: FindInto needs a slice of structs, got %T", dest)
+	}
+
+	var s structScanner
+	return f.FindContext(ctx, func(rows *sql.Rows) error {
+		elem := reflect.New(elemType)
+		if err := s.scan(f, rows, elem.Elem()); err != nil {
+			return err
+		}
+
+		if isPtr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+		return nil
+	})
+}
+
+// FindByExample is FindInto filtered by example, a struct or pointer to one:
+// each non-nil pointer field adds `col = value`, with the column named as in
+// NewTableMapFromStruct. Non-pointer fields are ignored, since there's no
+// telling a zero value from an unset one. f isn't changed.
+func (f *TableMap) FindByExample(example interface{}, dest interface{}) error {
+	return f.FindByExampleContext(context.Background(), example, dest)
+}
+
+func (f *TableMap) FindByExampleContext(ctx context.Context, example interface{}, dest interface{}) error {
+	v := reflect.ValueOf(example)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("dbtools: FindByExample needs a struct, got %T", example)
+	}
+
+	tm := f.Clone()
+	for i := 0; i < v.NumField(); i++ {
+		col, _, ok := structColumn(v.Type().Field(i))
+		fv := v.Field(i)
+		if !ok || fv.Kind() != reflect.Ptr || fv.IsNil() {
+			continue
+		}
+		tm.Where(col, "=", fv.Elem().Interface())
+	}
+	return tm.FindIntoContext(ctx, dest)
+}
+
+// structScanner scans rows into structs, matching the columns rows.Columns
+// says it has to fields, so it works whatever was selected or joined. The
+// columns are matched on the first row, to a setter and read hook each, and
+// reused for the rest.
+type structScanner struct {
+	typ     reflect.Type
+	setters []setter
+	reads   []TableMapOutput
+
+	// reused for every row, since Scan copies out of them
+	targets []interface{}
+	raws    []sql.NullString
+}
+
+func (s *structScanner) scan(f *TableMap, rows *sql.Rows, v reflect.Value) error {
+	if s.typ != v.Type() {
+		if err := s.prepare(f, rows, v.Type()); err != nil {
+			return err
+		}
+	}
+
+	// Columns with a read hook (like JSONCol) scan into a string, which the
+	// hook decodes into the field afterwards. The rest scan straight into
+	// the field; pointer fields are what make NULLs work, since database/sql
+	// sets them to nil for NULL and allocates a fresh value otherwise.
+	for i, set := range s.setters {
+		if s.reads[i] != nil {
+			s.targets[i] = &s.raws[i]
+		} else {
+			s.targets[i] = set(v).Addr().Interface()
+		}
+	}
+	if err := rows.Scan(s.targets...); err != nil {
+		return err
+	}
+
+	for i, read := range s.reads {
+		if read == nil {
+			continue
+		}
+		if err := read(s.raws[i], s.setters[i](v).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepare looks up the setter for each column, failing with the column's name
+// if t has no field for it, so a struct that has fallen behind the query
+// doesn't silently lose it.
+func (s *structScanner) prepare(f *TableMap, rows *sql.Rows, t reflect.Type) error {
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	fields := settersFor(t)
+	s.setters = make([]setter, len(cols))
+	s.reads = make([]TableMapOutput, len(cols))
+	for i, col := range cols {
+		set, ok := fields.lookup(col, f.SnakeCase)
+		if !ok {
+			return fmt.Errorf("dbtools: query returns %d columns, but %s has no field for column %q", len(cols), t, col)
+		}
+		s.setters[i] = set
+		s.reads[i] = f.Fields[col].read
+	}
+	s.targets = make([]interface{}, len(cols))
+	s.raws = make([]sql.NullString, len(cols))
+	s.typ = t
+	return nil
+}
+
+// ScanStruct scans the current row of rows into the struct dest points to,
+// matching columns to fields the same way FindInto does (without SnakeCase).
+// It's for queries the TableMap didn't build. It matches the columns afresh
+// each call; use a Scanner to do that once for all the rows.
+func ScanStruct(rows *sql.Rows, dest interface{}) error {
+	var s Scanner
+	return s.Scan(rows, dest)
+}
+
+// Scanner is ScanStruct for a whole result set: the columns are matched to
+// fields on the first row and reused for the rest (or until dest is another
+// struct type). The zero value is ready to use, once per rows:
+//
+//	var s dbtools.Scanner
+//	for rows.Next() {
+//		var m Message
+//		if err := s.Scan(rows, &m); err != nil {
+//			return err
+//		}
+//	}
+type Scanner struct {
+	s structScanner
+}
+
+func (sc *Scanner) Scan(rows *sql.Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dbtools: ScanStruct needs a pointer to a struct, got %T", dest)
+	}
+	return sc.s.scan(&TableMap{}, rows, v.Elem())
+}
+
+// setter gets a struct's field for one column, ready to be scanned into.
+type setter func(v reflect.Value) reflect.Value
+
+// structSetters maps columns to fields for one struct type: tagged by their
+// db tag, untagged by their lowercased name.
+type structSetters struct {
+	tagged   map[string]setter
+	untagged map[string]setter
+}
+
+// lookup finds col's field. With snake set, a column that doesn't match as it
+// is gets another try without its underscores: author_name as authorname.
+func (s structSetters) lookup(col string, snake bool) (setter, bool) {
+	if set, ok := s.tagged[col]; ok {
+		return set, true
+	}
+	set, ok := s.untagged[strings.ToLower(col)]
+	if !ok && snake {
+		set, ok = s.untagged[strings.ToLower(strings.ReplaceAll(col, "_", ""))]
+	}
+	return set, ok
+}
+
+// setterCache holds the structSetters for every type seen so far, so the
+// struct is only walked with reflection once rather than for every row.
+var setterCache sync.Map
+
+func settersFor(t reflect.Type) structSetters {
+	if s, ok := setterCache.Load(t); ok {
+		return s.(structSetters)
+	}
+
+	s := structSetters{tagged: map[string]setter{}, untagged: map[string]setter{}}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, ok := structColumn(sf)
+		if !ok {
+			continue
+		}
+
+		i := i
+		set := func(v reflect.Value) reflect.Value { return v.Field(i) }
+		if sf.Tag.Get("db") == "" {
+			if _, dup := s.untagged[name]; !dup {
+				s.untagged[name] = set
+			}
+		} else if _, dup := s.tagged[name]; !dup {
+			s.tagged[name] = set
+		}
+	}
+
+	setterCache.Store(t, s)
+	return s
+}
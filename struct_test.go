@@ -0,0 +1,93 @@
+package dbtools
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+// benchScanDB is a messages table with 100 rows.
+func benchScanDB(b *testing.B) *sql.DB {
+	b.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	b.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec("CREATE TABLE messages (id INTEGER PRIMARY KEY, title TEXT, body TEXT)"); err != nil {
+		b.Fatal(err)
+	}
+	for i := 1; i <= 100; i++ {
+		if _, err := db.Exec("INSERT INTO messages VALUES (?, ?, ?)", i, fmt.Sprint("title ", i), "body"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return db
+}
+
+func BenchmarkScanStruct(b *testing.B) {
+	db := benchScanDB(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT id, title, body FROM messages")
+		if err != nil {
+			b.Fatal(err)
+		}
+		var out []testMessage
+		for rows.Next() {
+			var m testMessage
+			if err := ScanStruct(rows, &m); err != nil {
+				b.Fatal(err)
+			}
+			out = append(out, m)
+		}
+		rows.Close()
+	}
+}
+
+func BenchmarkScanner(b *testing.B) {
+	db := benchScanDB(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT id, title, body FROM messages")
+		if err != nil {
+			b.Fatal(err)
+		}
+		var s Scanner
+		var out []testMessage
+		for rows.Next() {
+			var m testMessage
+			if err := s.Scan(rows, &m); err != nil {
+				b.Fatal(err)
+			}
+			out = append(out, m)
+		}
+		rows.Close()
+	}
+}
+
+// BenchmarkScanClosure is the hand-written Scan that ScanStruct replaces.
+func BenchmarkScanClosure(b *testing.B) {
+	db := benchScanDB(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query("SELECT id, title, body FROM messages")
+		if err != nil {
+			b.Fatal(err)
+		}
+		var out []testMessage
+		for rows.Next() {
+			var m testMessage
+			if err := rows.Scan(&m.ID, &m.Title, &m.Body); err != nil {
+				b.Fatal(err)
+			}
+			out = append(out, m)
+		}
+		rows.Close()
+	}
+}